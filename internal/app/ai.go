@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/ai"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+	"github.com/kristofferahl/mavis/internal/pkg/lint"
+	"github.com/kristofferahl/mavis/internal/pkg/ui"
+)
+
+// suggest returns a func generating AI suggestions for the staged changes. Initial suggestions
+// are cached when enabled, regenerated suggestions using hints are always requested.
+func suggest(c *config.Config) ui.Suggest {
+	return func(ctx context.Context, hints ai.FieldDefaults, partial func(ai.FieldDefaults)) ([]ai.FieldDefaults, error) {
+		gitDiff, err := exec.CommandContext(ctx, "git", "diff", "--cached").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git diff, %w", err)
+		}
+
+		gitBranch := ""
+		if branchOutput, err := exec.CommandContext(ctx, "git", "branch", "--show-current").Output(); err == nil {
+			gitBranch = strings.TrimSpace(string(branchOutput))
+		}
+
+		cfg := c.AI
+		if hints != nil {
+			cfg.Cache.Enabled = false
+		}
+		client, err := ai.NewClient(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AI client, %w", err)
+		}
+		prompt, err := ai.GeneratePrompt(c, string(gitDiff), gitBranch, examples(ctx, c))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate prompt, %w", err)
+		}
+		return ai.Generate(ctx, client, prompt.WithHints(hints), partial)
+	}
+}
+
+// examples returns recent commit messages of the repository, optionally only those passing lint
+func examples(ctx context.Context, c *config.Config) []string {
+	count := c.AI.Examples.Count
+	if count <= 0 {
+		return nil
+	}
+
+	var linter *lint.Linter
+	limit := count
+	if c.AI.Examples.Lint {
+		l, err := lint.New(c)
+		if err != nil {
+			log.Debug("failed to create linter for examples", "error", err)
+			return nil
+		}
+		linter = l
+		// look further back as some messages may not pass lint
+		limit = count * 3
+	}
+
+	messages, err := gitLogMessages(ctx, false, fmt.Sprintf("--max-count=%d", limit))
+	if err != nil {
+		log.Debug("failed to read commit history for examples", "error", err)
+		return nil
+	}
+
+	examples := make([]string, 0, count)
+	for _, m := range messages {
+		if len(examples) == count {
+			break
+		}
+		message := lint.Clean(m.Message)
+		if message == "" {
+			continue
+		}
+		if linter != nil && !linter.Lint(message).OK() {
+			log.Debug("skipping example not passing lint", "commit", m.Name)
+			continue
+		}
+		examples = append(examples, message)
+	}
+	log.Debug("collected commit examples", "count", len(examples))
+	return examples
+}
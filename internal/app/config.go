@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v3"
+)
+
+type ConfigOptions struct {
+	Origins bool
+}
+
+var (
+	configOpt ConfigOptions
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the resolved configuration",
+	Long: `Show the configuration resolved from the user config, matching includes
+and the project config. Use --origins to list the file each value was read from.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: false,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if configOpt.Origins {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			for _, o := range c.Origins() {
+				fmt.Fprintf(w, "%s\t%s\n", o.Key, o.File)
+			}
+			return w.Flush()
+		}
+
+		b, err := yaml.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config, %w", err)
+		}
+		_, err = out.Write(b)
+		return err
+	},
+}
+
+// loadConfig reads the user config, creating it if it doesn't exist, and applies environment overrides
+func loadConfig() (*config.Config, error) {
+	configFile, err := appConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config path, %w", err)
+	}
+
+	log.Debug("config file", "path", configFile)
+
+	// create config, set the root config path
+	c := config.New(configFile)
+
+	// automatically create config file if it doesn't exist
+	if !c.Exists() {
+		if err := c.Write(); err != nil {
+			return nil, err
+		}
+	}
+
+	// read config file
+	if err := c.Read(); err != nil {
+		return nil, err
+	}
+
+	// env overrides
+	theme := os.Getenv("MAVIS_THEME")
+	if len(theme) > 0 {
+		log.Debug("overriding theme from env", "theme", theme)
+		c.Theme = theme
+	}
+	chip := os.Getenv("MAVIS_CHIP")
+	if len(chip) > 0 {
+		log.Debug("overriding chip from env", "chip", chip)
+		c.Chip = chip
+	}
+
+	c.ResolveOptions()
+
+	return c, nil
+}
+
+func appConfigPath() (string, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config dir, %w", err)
+	}
+	appConfigDir := path.Join(userConfigDir, "mavis")
+	return path.Join(appConfigDir, "config.yaml"), nil
+}
+
+func init() {
+	configCmd.Flags().BoolVarP(&configOpt.Origins, "origins", "", false, "list the file each configuration value was read from")
+	rootCmd.AddCommand(configCmd)
+}
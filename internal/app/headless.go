@@ -0,0 +1,80 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/commit"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// runHeadless renders and commits a message from field values provided by flags or stdin
+func runHeadless(cmd *cobra.Command, c *config.Config) error {
+	values := make(map[string]interface{})
+
+	if opt.Stdin {
+		b, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("failed to read stdin, %w", err)
+		}
+		if err := json.Unmarshal(b, &values); err != nil {
+			return fmt.Errorf("invalid values JSON on stdin, %w", err)
+		}
+	}
+
+	for _, s := range opt.Set {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("invalid --set value %q, expected title=value", s)
+		}
+		values[key] = value
+	}
+
+	for key := range values {
+		if !hasField(c, key) {
+			return fmt.Errorf("unknown field: %s", key)
+		}
+	}
+
+	// fall back to configured defaults for values not provided
+	for _, f := range c.Fields {
+		if _, ok := values[f.Title]; !ok && f.Default != nil {
+			values[f.Title] = f.Default
+		}
+	}
+	log.Debug("non-interactive mode", "values", values)
+
+	values, err := c.CoerceValues(values)
+	if err != nil {
+		return fmt.Errorf("invalid field values, %w", err)
+	}
+	templateValues, err := c.TemplateValuesFrom(values)
+	if err != nil {
+		return fmt.Errorf("invalid field values, %w", err)
+	}
+
+	renderer := commit.NewRenderer(c.Template)
+	message, err := renderer.Render(templateValues)
+	if err != nil {
+		return err
+	}
+
+	if opt.Print {
+		fmt.Fprintln(cmd.OutOrStdout(), message)
+		return nil
+	}
+	return gitCommit(cmd, message)
+}
+
+func hasField(c *config.Config, title string) bool {
+	for _, f := range c.Fields {
+		if f.Title == title {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/hook"
+	"github.com/kristofferahl/mavis/internal/pkg/lint"
+	"github.com/kristofferahl/mavis/internal/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+type HookOptions struct {
+	Force bool
+}
+
+var (
+	hookOpt HookOptions
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage and run the mavis git hooks",
+	Long: `Manage and run the mavis git hooks.
+
+Once installed, a plain "git commit" opens the mavis commit UI through the
+prepare-commit-msg hook and the final message is linted by the commit-msg hook.`,
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:           "install",
+	Short:         "Install the prepare-commit-msg and commit-msg hooks",
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: false,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := hook.Dir()
+		if err != nil {
+			return err
+		}
+		if err := hook.Install(dir, hookOpt.Force); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "hooks installed in %s\n", dir)
+		return nil
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:           "uninstall",
+	Short:         "Remove the hooks installed by mavis",
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: false,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := hook.Dir()
+		if err != nil {
+			return err
+		}
+		if err := hook.Uninstall(dir); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "hooks removed from %s\n", dir)
+		return nil
+	},
+}
+
+var hookPrepareCommitMsgCmd = &cobra.Command{
+	Use:           hook.PrepareCommitMsg + " <file> [source] [sha]",
+	Short:         "Open the commit UI and write the message for git to use",
+	Args:          cobra.RangeArgs(1, 3),
+	SilenceUsage:  true,
+	SilenceErrors: false,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// only plain commits, messages from -m, templates, merges etc. are left untouched
+		if len(args) > 1 && args[1] != "" {
+			log.Debug("skipping commit UI", "source", args[1])
+			return nil
+		}
+
+		// git runs hooks without a terminal attached to stdin, talk to the terminal directly
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			log.Debug("no terminal available, skipping commit UI", "error", err)
+			return nil
+		}
+		defer tty.Close()
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		model := ui.NewCommitUI(*c)
+		if c.AI.Enabled {
+			model = model.WithSuggestions(cmd.Context(), suggest(c))
+		}
+
+		message, ok, err := runCommitUI(model, tea.WithInput(tty), tea.WithOutput(tty))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		existing, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read commit message file, %w", err)
+		}
+		content := message + "\n" + strings.TrimRight(string(existing), "\n") + "\n"
+		if err := os.WriteFile(args[0], []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write commit message file, %w", err)
+		}
+		return nil
+	},
+}
+
+var hookCommitMsgCmd = &cobra.Command{
+	Use:           hook.CommitMsg + " <file>",
+	Short:         "Lint the commit message written by git",
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: false,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		linter, err := lint.New(c)
+		if err != nil {
+			return err
+		}
+
+		m, err := readMessage(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		if lint.Generated(m.Message) || merging() {
+			log.Debug("skipping lint of message generated by git")
+			return nil
+		}
+
+		r := linter.Lint(m.Message)
+		if !r.OK() {
+			printLintResult(cmd.ErrOrStderr(), "commit message", r)
+			return fmt.Errorf("commit message failed lint")
+		}
+		return nil
+	},
+}
+
+// merging returns true while git is concluding a merge
+func merging() bool {
+	path, err := exec.Command("git", "rev-parse", "--git-path", "MERGE_HEAD").Output()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(strings.TrimSpace(string(path)))
+	return err == nil
+}
+
+func init() {
+	hookInstallCmd.Flags().BoolVarP(&hookOpt.Force, "force", "f", false, "replace existing hooks not installed by mavis")
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+	hookCmd.AddCommand(hookPrepareCommitMsgCmd)
+	hookCmd.AddCommand(hookCommitMsgCmd)
+	rootCmd.AddCommand(hookCmd)
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kristofferahl/mavis/internal/pkg/lint"
+	"github.com/spf13/cobra"
+)
+
+type LintOptions struct {
+	Range  string
+	Merges bool
+}
+
+var (
+	lintOpt LintOptions
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [file]",
+	Short: "Validate commit messages against the configured template",
+	Long: `Validate commit messages against the structure implied by the configured
+template and fields. Select values must be one of the configured options,
+required fields must be present, values must pass the validation rules of
+their field and the subject must not exceed the configured maximum length.
+
+The message is read from the given file, from stdin when the file is "-" or
+omitted, or from the commits in a git revision range, e.g.
+
+  mavis lint --range origin/main..HEAD`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: false,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		linter, err := lint.New(c)
+		if err != nil {
+			return err
+		}
+
+		messages := make([]lintMessage, 0)
+		if lintOpt.Range != "" {
+			messages, err = gitLogMessages(cmd.Context(), lintOpt.Merges, lintOpt.Range)
+			if err != nil {
+				return err
+			}
+		} else {
+			m, err := readMessage(cmd, args)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, m)
+		}
+
+		failed := 0
+		out := cmd.OutOrStdout()
+		for _, m := range messages {
+			r := linter.Lint(m.Message)
+			if !r.OK() {
+				failed++
+			}
+			printLintResult(out, m.Name, r)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d commit messages failed lint", failed, len(messages))
+		}
+		return nil
+	},
+}
+
+func printLintResult(out io.Writer, name string, r lint.Result) {
+	if r.OK() {
+		fmt.Fprintf(out, "✔ %s %s\n", name, r.Subject())
+		return
+	}
+	fmt.Fprintf(out, "✘ %s %s\n", name, r.Subject())
+	for _, err := range r.Errors {
+		fmt.Fprintf(out, "  - %s\n", err)
+	}
+}
+
+type lintMessage struct {
+	Name    string
+	Message string
+}
+
+func readMessage(cmd *cobra.Command, args []string) (lintMessage, error) {
+	if len(args) == 0 || args[0] == "-" {
+		b, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return lintMessage{}, fmt.Errorf("failed to read stdin, %w", err)
+		}
+		return lintMessage{Name: "stdin", Message: string(b)}, nil
+	}
+
+	b, err := os.ReadFile(args[0])
+	if err != nil {
+		return lintMessage{}, fmt.Errorf("failed to read message file, %w", err)
+	}
+	return lintMessage{Name: args[0], Message: string(b)}, nil
+}
+
+// gitLogMessages returns the messages of the commits selected by the git log arguments, e.g. a revision range
+func gitLogMessages(ctx context.Context, merges bool, logArgs ...string) ([]lintMessage, error) {
+	args := []string{"log", "--format=%h%x00%B%x1e"}
+	if !merges {
+		args = append(args, "--no-merges")
+	}
+	args = append(args, logArgs...)
+
+	var stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "git", args...)
+	c.Stderr = &stderr
+	output, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed, %w\n%s", err, stderr.String())
+	}
+
+	messages := make([]lintMessage, 0)
+	for _, record := range strings.Split(string(output), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		hash, message, ok := strings.Cut(record, "\x00")
+		if !ok {
+			continue
+		}
+		messages = append(messages, lintMessage{Name: hash, Message: message})
+	}
+	return messages, nil
+}
+
+func init() {
+	lintCmd.Flags().StringVarP(&lintOpt.Range, "range", "r", "", "lint the commits in a git revision range, e.g. origin/main..HEAD")
+	lintCmd.Flags().BoolVarP(&lintOpt.Merges, "merges", "", false, "include merge commits when linting a revision range")
+	rootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,237 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+const (
+	anthropicBaseURL  = "https://api.anthropic.com"
+	anthropicVersion  = "2023-06-01"
+	anthropicToolName = "set_field_defaults"
+)
+
+// AnthropicClient implements the Client interface using the Anthropic Messages API
+type AnthropicClient struct {
+	client *http.Client
+	apiKey string
+	config config.AnthropicConfig
+}
+
+// NewAnthropicClient creates a new Anthropic client. A custom base url only receives
+// an API key when api_key_env is set explicitly.
+func NewAnthropicClient(c config.AnthropicConfig) (*AnthropicClient, error) {
+	if c.BaseURL == "" {
+		c.BaseURL = anthropicBaseURL
+	}
+	apiKeyEnv := c.APIKeyEnv
+	if apiKeyEnv == "" && strings.TrimSuffix(c.BaseURL, "/") == anthropicBaseURL {
+		apiKeyEnv = "ANTHROPIC_API_KEY"
+	}
+	apiKey := ""
+	if apiKeyEnv != "" {
+		apiKey = os.Getenv(apiKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("%s environment variable is not set", apiKeyEnv)
+		}
+	}
+
+	return &AnthropicClient{
+		client: http.DefaultClient,
+		apiKey: apiKey,
+		config: c,
+	}, nil
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float64              `json:"temperature"`
+	Messages    []anthropicMessage   `json:"messages"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// APIError is returned when a provider responds with an error status
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// GenerateFieldDefaults generates default values for fields based on a prepared prompt.
+// The response is structured by forcing the model to call a tool with the alternative
+// suggestions as input, each suggestion matching the field schema.
+func (c *AnthropicClient) GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error) {
+	log.Debug("generating commit defaults", "client", "anthropic", "model", c.config.Model, "count", prompt.Count)
+
+	count := max(prompt.Count, 1)
+	var res anthropicResponse
+	err := c.post(ctx, "/v1/messages", anthropicRequest{
+		Model:       c.config.Model,
+		MaxTokens:   c.config.MaxTokens * count,
+		Temperature: c.config.Temperature,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: prompt.Text},
+		},
+		Tools: []anthropicTool{
+			{
+				Name:        anthropicToolName,
+				Description: fmt.Sprintf("Set %d alternative suggestions for the default values of the commit message fields, best first", count),
+				InputSchema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"suggestions": map[string]any{
+							"type":     "array",
+							"items":    prompt.Schema(),
+							"minItems": 1,
+							"maxItems": count,
+						},
+					},
+					"required": []string{"suggestions"},
+				},
+			},
+		},
+		ToolChoice: &anthropicToolChoice{
+			Type: "tool",
+			Name: anthropicToolName,
+		},
+	}, &res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	log.Debug(
+		"received AI response",
+		"blocks",
+		len(res.Content),
+		"total_tokens",
+		res.Usage.InputTokens+res.Usage.OutputTokens,
+	)
+
+	suggestions := make([]FieldDefaults, 0, count)
+	text := strings.Builder{}
+	for _, block := range res.Content {
+		switch block.Type {
+		case "tool_use":
+			log.Debug("AI tool input", "input", string(block.Input))
+			var input struct {
+				Suggestions []FieldDefaults `json:"suggestions"`
+			}
+			if err := json.Unmarshal(block.Input, &input); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal AI response: %w", err)
+			}
+			for _, defaults := range input.Suggestions {
+				suggestions = append(suggestions, defaults.Validate(prompt.Fields))
+			}
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+
+	if len(suggestions) > 0 {
+		return suggestions, nil
+	}
+
+	// fall back to a JSON object in the text of the response
+	response := text.String()
+	if response == "" {
+		return nil, fmt.Errorf("AI response is empty")
+	}
+	log.Debug("AI response text", "response", response)
+
+	defaults, err := ParseFieldDefaults(response)
+	if err != nil {
+		return nil, err
+	}
+	return []FieldDefaults{defaults.Validate(prompt.Fields)}, nil
+}
+
+func (c *AnthropicClient) post(ctx context.Context, path string, body any, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.config.BaseURL, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("x-api-key", c.apiKey)
+	}
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err = io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if res.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(b, &e)
+		return &APIError{
+			StatusCode: res.StatusCode,
+			Type:       e.Error.Type,
+			Message:    e.Error.Message,
+		}
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
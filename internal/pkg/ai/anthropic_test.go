@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+var testFields = []*config.Field{
+	{
+		Type:  "select",
+		Title: "type",
+		Options: []config.SelectOption{
+			{Value: "feat"},
+			{Value: "fix"},
+		},
+	},
+	{
+		Type:  "input",
+		Title: "summary",
+	},
+	{
+		Type:  "confirm",
+		Title: "breaking",
+	},
+}
+
+// newTestAnthropicClient starts a stand-in for the Messages API responding with the handler
+func newTestAnthropicClient(t *testing.T, handler http.HandlerFunc) *AnthropicClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("TEST_ANTHROPIC_KEY", "secret")
+	client, err := NewAnthropicClient(config.AnthropicConfig{
+		Model:     "claude-test",
+		MaxTokens: 100,
+		APIKeyEnv: "TEST_ANTHROPIC_KEY",
+		BaseURL:   server.URL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func respond(t *testing.T, w http.ResponseWriter, status int, body string) {
+	t.Helper()
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Errorf("failed to write response: %v", err)
+	}
+}
+
+func TestAnthropicRequest(t *testing.T) {
+	client := newTestAnthropicClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %q, want /v1/messages", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "secret" {
+			t.Errorf("x-api-key = %q, want secret", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != anthropicVersion {
+			t.Errorf("anthropic-version = %q, want %s", got, anthropicVersion)
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			respond(t, w, http.StatusBadRequest, `{"type": "error", "error": {"type": "invalid_request_error", "message": "invalid json"}}`)
+			return
+		}
+		if req.Model != "claude-test" {
+			t.Errorf("model = %q, want claude-test", req.Model)
+		}
+		if req.MaxTokens != 200 {
+			t.Errorf("max_tokens = %d, want 200 for 2 suggestions", req.MaxTokens)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Content != "prompt" {
+			t.Errorf("messages = %+v, want the prompt as a single user message", req.Messages)
+		}
+		if req.ToolChoice == nil || req.ToolChoice.Type != "tool" || req.ToolChoice.Name != anthropicToolName {
+			t.Errorf("tool_choice = %+v, want the %s tool", req.ToolChoice, anthropicToolName)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Name != anthropicToolName {
+			t.Errorf("tools = %+v, want the %s tool", req.Tools, anthropicToolName)
+		}
+
+		respond(t, w, http.StatusOK, `{"content": [{"type": "tool_use", "input": {"suggestions": [{"type": "fix"}]}}]}`)
+	})
+
+	if _, err := client.GenerateFieldDefaults(context.Background(), Prompt{Text: "prompt", Fields: testFields, Count: 2}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAnthropicToolUse(t *testing.T) {
+	client := newTestAnthropicClient(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(t, w, http.StatusOK, `{"content": [
+			{"type": "text", "text": "Setting the values."},
+			{"type": "tool_use", "name": "set_field_defaults", "input": {"suggestions": [
+				{"type": "fix", "summary": "handle empty config", "breaking": false},
+				{"type": "Feat", "summary": "add config", "breaking": "yes", "unknown": "x"}
+			]}}
+		]}`)
+	})
+
+	suggestions, err := client.GenerateFieldDefaults(context.Background(), Prompt{Text: "prompt", Fields: testFields, Count: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("got %d suggestions, want 2", len(suggestions))
+	}
+	if suggestions[0]["type"] != "fix" || suggestions[0]["summary"] != "handle empty config" || suggestions[0]["breaking"] != false {
+		t.Errorf("first suggestion = %v", suggestions[0])
+	}
+	// values are coerced to the fields and unknown fields dropped
+	if suggestions[1]["type"] != "feat" || suggestions[1]["breaking"] != true {
+		t.Errorf("second suggestion = %v, want coerced values", suggestions[1])
+	}
+	if _, ok := suggestions[1]["unknown"]; ok {
+		t.Errorf("second suggestion = %v, want unknown field dropped", suggestions[1])
+	}
+}
+
+func TestAnthropicTextFallback(t *testing.T) {
+	client := newTestAnthropicClient(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(t, w, http.StatusOK, `{"content": [
+			{"type": "text", "text": "Here you go:\n`+"```json"+`\n{\"type\": \"feat\", \"summary\": \"add config\"}\n`+"```"+`"}
+		]}`)
+	})
+
+	suggestions, err := client.GenerateFieldDefaults(context.Background(), Prompt{Text: "prompt", Fields: testFields, Count: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0]["type"] != "feat" || suggestions[0]["summary"] != "add config" {
+		t.Errorf("suggestions = %v, want the values of the JSON object in the text", suggestions)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{529, true},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			client := newTestAnthropicClient(t, func(w http.ResponseWriter, r *http.Request) {
+				respond(t, w, tt.status, `{"type": "error", "error": {"type": "test_error", "message": "failed"}}`)
+			})
+
+			_, err := client.GenerateFieldDefaults(context.Background(), Prompt{Text: "prompt", Fields: testFields, Count: 1})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Type != "test_error" || apiErr.Message != "failed" {
+				t.Errorf("error = %+v", apiErr)
+			}
+			if retryable(err) != tt.retryable {
+				t.Errorf("retryable = %v, want %v", retryable(err), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestAnthropicCustomEndpointWithoutKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-api-key"); got != "" {
+			t.Errorf("x-api-key = %q, want no key sent to a custom endpoint", got)
+		}
+		respond(t, w, http.StatusOK, `{"content": [{"type": "tool_use", "input": {"suggestions": [{"type": "fix"}]}}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("ANTHROPIC_API_KEY", "secret")
+	client, err := NewAnthropicClient(config.AnthropicConfig{
+		Model:     "claude-test",
+		MaxTokens: 100,
+		BaseURL:   server.URL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	if _, err := client.GenerateFieldDefaults(context.Background(), Prompt{Text: "prompt", Fields: testFields, Count: 1}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
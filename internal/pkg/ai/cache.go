@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+	"github.com/kristofferahl/mavis/internal/pkg/version"
+)
+
+// CachedClient wraps a client, caching its suggestions on disk keyed by a hash of the
+// prompt, provider and model
+type CachedClient struct {
+	client Client
+	dir    string
+	ttl    time.Duration
+	key    string
+}
+
+// CacheDir returns the directory AI responses are cached in
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, version.Name, "ai"), nil
+}
+
+// NewCachedClient creates a client caching the suggestions of the client for the provider of the config
+func NewCachedClient(client Client, cfg config.AIConfig) (*CachedClient, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache dir: %w", err)
+	}
+
+	var key []string
+	switch cfg.Provider {
+	case "openai":
+		key = []string{cfg.Provider, cfg.OpenAI.Model, cfg.OpenAI.BaseURL}
+	case "anthropic":
+		key = []string{cfg.Provider, cfg.Anthropic.Model, cfg.Anthropic.BaseURL}
+	default:
+		key = []string{cfg.Provider}
+	}
+
+	return &CachedClient{
+		client: client,
+		dir:    dir,
+		ttl:    cfg.Cache.TTL,
+		key:    strings.Join(key, "\x00"),
+	}, nil
+}
+
+// GenerateFieldDefaults returns cached suggestions for the prompt, or generates and caches them
+func (c *CachedClient) GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error) {
+	return c.StreamFieldDefaults(ctx, prompt, nil)
+}
+
+// StreamFieldDefaults returns cached suggestions for the prompt, or streams and caches them
+func (c *CachedClient) StreamFieldDefaults(ctx context.Context, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error) {
+	file := filepath.Join(c.dir, c.hash(prompt)+".json")
+	if suggestions, ok := c.read(file); ok {
+		log.Debug("using cached AI suggestions", "file", file)
+		return suggestions, nil
+	}
+
+	suggestions, err := Generate(ctx, c.client, prompt, partial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.write(file, suggestions); err != nil {
+		log.Debug("failed to cache AI suggestions", "file", file, "error", err)
+	}
+	return suggestions, nil
+}
+
+func (c *CachedClient) hash(prompt Prompt) string {
+	schema, _ := json.Marshal(prompt.Schema())
+	h := sha256.New()
+	for _, s := range []string{c.key, fmt.Sprint(prompt.Count), string(schema), prompt.Text} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *CachedClient) read(file string) ([]FieldDefaults, bool) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, false
+	}
+	if c.expired(info) {
+		log.Debug("cached AI suggestions expired", "file", file)
+		_ = os.Remove(file)
+		return nil, false
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var suggestions []FieldDefaults
+	if err := json.Unmarshal(b, &suggestions); err != nil || len(suggestions) == 0 {
+		log.Debug("ignoring invalid cached AI suggestions", "file", file, "error", err)
+		return nil, false
+	}
+	return suggestions, true
+}
+
+// write caches the suggestions, removing expired entries of the cache
+func (c *CachedClient) write(file string, suggestions []FieldDefaults) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && c.expired(info) {
+			_ = os.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+
+	b, err := json.Marshal(suggestions)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0600)
+}
+
+func (c *CachedClient) expired(info os.FileInfo) bool {
+	return c.ttl > 0 && time.Since(info.ModTime()) > c.ttl
+}
@@ -2,24 +2,78 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/charmbracelet/log"
 	"github.com/kristofferahl/mavis/internal/pkg/config"
 )
 
 type FieldDefaults map[string]interface{}
 
-// Client interface for AI-powered commit message generation
+// Client interface for AI-powered commit message generation. GenerateFieldDefaults
+// returns up to prompt.Count alternative suggestions, best first.
 type Client interface {
-	GenerateFieldDefaults(ctx context.Context, prompt string) (FieldDefaults, error)
+	GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error)
 }
 
-// NewClient creates a new AI client based on the provider in config
+// NewClient creates a new AI client based on the provider in config. Requests are retried
+// and cached as configured, falling back through the configured fallback providers on failure.
 func NewClient(cfg config.AIConfig) (Client, error) {
+	if len(cfg.Fallbacks) == 0 {
+		return newClient(cfg)
+	}
+
+	providers := append([]config.ProviderConfig{{
+		Provider:  cfg.Provider,
+		OpenAI:    cfg.OpenAI,
+		Anthropic: cfg.Anthropic,
+	}}, cfg.Fallbacks...)
+
+	fallback := &FallbackClient{}
+	errs := make([]error, 0)
+	for _, p := range providers {
+		client, err := newClient(cfg.WithProvider(p))
+		if err != nil {
+			log.Debug("skipping AI provider", "provider", p.Provider, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Provider, err))
+			continue
+		}
+		fallback.clients = append(fallback.clients, client)
+		fallback.providers = append(fallback.providers, p.Provider)
+	}
+	if len(fallback.clients) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return fallback, nil
+}
+
+// newClient creates a client for the provider in config, retrying and caching its requests
+func newClient(cfg config.AIConfig) (Client, error) {
+	var client Client
 	switch cfg.Provider {
 	case "openai":
-		return NewOpenAIClient(cfg.OpenAI)
+		c, err := NewOpenAIClient(cfg.OpenAI)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	case "anthropic":
+		c, err := NewAnthropicClient(cfg.Anthropic)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	case "heuristic":
+		// runs locally, nothing to retry or cache
+		return NewHeuristicClient(), nil
 	default:
 		return nil, fmt.Errorf("unsupported AI provider: %s", cfg.Provider)
 	}
+
+	client = NewRetryClient(client, cfg.Provider, cfg.Timeout, cfg.Retries)
+	if cfg.Cache.Enabled {
+		return NewCachedClient(client, cfg)
+	}
+	return client, nil
 }
@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+)
+
+// FallbackClient tries a list of clients in order, returning the suggestions of the first succeeding
+type FallbackClient struct {
+	clients   []Client
+	providers []string
+}
+
+// GenerateFieldDefaults generates suggestions using the first succeeding client
+func (c *FallbackClient) GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error) {
+	return c.StreamFieldDefaults(ctx, prompt, nil)
+}
+
+// StreamFieldDefaults streams suggestions using the first succeeding client
+func (c *FallbackClient) StreamFieldDefaults(ctx context.Context, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error) {
+	errs := make([]error, 0, len(c.clients))
+	for i, client := range c.clients {
+		suggestions, err := Generate(ctx, client, prompt, partial)
+		if err == nil {
+			return suggestions, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Debug("AI provider failed", "provider", c.providers[i], "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", c.providers[i], err))
+	}
+	return nil, fmt.Errorf("all AI providers failed, %w", errors.Join(errs...))
+}
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+// branchTypes maps common branch prefixes to commit types
+var branchTypes = map[string]string{
+	"feature":  "feat",
+	"bug":      "fix",
+	"bugfix":   "fix",
+	"hotfix":   "fix",
+	"doc":      "docs",
+	"refactor": "refactor",
+	"chore":    "chore",
+}
+
+var (
+	ticket          = regexp.MustCompile(`^[A-Za-z]+-[0-9]+[-_]?`)
+	exportedDecl    = regexp.MustCompile(`^\s*(?:func\s+(?:\([^)]*\)\s*)?|type\s+)([A-Z]\w*)`)
+	breakingKeys    = []string{"breaking"}
+	scopeKeys       = []string{"scope"}
+	descriptionKeys = []string{"description", "summary", "subject"}
+)
+
+// HeuristicClient implements the Client interface without a model, inferring
+// field defaults from the branch name and the staged changes
+type HeuristicClient struct{}
+
+// NewHeuristicClient creates a new heuristic client
+func NewHeuristicClient() *HeuristicClient {
+	return &HeuristicClient{}
+}
+
+// GenerateFieldDefaults generates default values for fields based on a prepared prompt
+func (c *HeuristicClient) GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error) {
+	log.Debug("generating commit defaults", "client", "heuristic", "branch", prompt.Branch, "files", len(prompt.Files))
+	defaults := FieldDefaults{}
+
+	prefix, slug, _ := strings.Cut(prompt.Branch, "/")
+	if slug == "" {
+		prefix = ""
+	}
+	commitType := strings.ToLower(prefix)
+	if t, ok := branchTypes[commitType]; ok {
+		commitType = t
+	}
+
+	for _, f := range prompt.Fields {
+		switch {
+		case f.Type == "select" && commitType != "":
+			for _, o := range f.Options {
+				if o.Value == commitType || o.Key == commitType {
+					defaults[f.Title] = o.Value
+				}
+			}
+
+		case f.Type == "confirm" && matches(f, breakingKeys):
+			defaults[f.Title] = removesExported(prompt.Diff)
+
+		case f.Type == "input" && matches(f, scopeKeys):
+			if scope := scope(prompt.Files); scope != "" {
+				defaults[f.Title] = scope
+			}
+
+		case f.Type == "input" && matches(f, descriptionKeys) && slug != "":
+			summary := ticket.ReplaceAllString(path.Base(slug), "")
+			defaults[f.Title] = strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(summary))
+		}
+	}
+
+	log.Debug("inferred commit defaults", "defaults", defaults)
+	return []FieldDefaults{defaults.Validate(prompt.Fields)}, nil
+}
+
+// matches returns true if a formatting key or the title of the field contains any of the keys
+func matches(f *config.Field, keys []string) bool {
+	for _, k := range keys {
+		if strings.Contains(strings.ToLower(f.Title), k) {
+			return true
+		}
+		for _, rule := range f.Formatting {
+			if strings.Contains(strings.ToLower(rule.Key), k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scope returns the most common Go package or top level directory of the files
+func scope(files []string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, f := range files {
+		dir := path.Dir(f)
+		if dir == "." {
+			continue
+		}
+		s := strings.Split(dir, "/")[0]
+		if strings.HasSuffix(f, ".go") {
+			s = path.Base(dir)
+		}
+		counts[s]++
+		if best == "" || counts[s] > counts[best] {
+			best = s
+		}
+	}
+	return best
+}
+
+// removesExported returns true if the diff removes exported Go functions or types without adding them back
+func removesExported(diff string) bool {
+	removed := make(map[string]bool)
+	added := make(map[string]bool)
+	for _, line := range strings.Split(diff, "\n") {
+		if len(line) < 2 || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+		m := exportedDecl.FindStringSubmatch(line[1:])
+		if m == nil {
+			continue
+		}
+		switch line[0] {
+		case '-':
+			removed[m[1]] = true
+		case '+':
+			added[m[1]] = true
+		}
+	}
+	for name := range removed {
+		if !added[name] {
+			log.Debug("exported identifier removed", "name", name)
+			return true
+		}
+	}
+	return false
+}
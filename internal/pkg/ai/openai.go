@@ -2,7 +2,6 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -10,6 +9,7 @@ import (
 	"github.com/kristofferahl/mavis/internal/pkg/config"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
 )
 
 // OpenAIClient implements the Client interface using OpenAI
@@ -18,15 +18,43 @@ type OpenAIClient struct {
 	config config.OpenAIConfig
 }
 
-// NewOpenAIClient creates a new OpenAI client
+// NewOpenAIClient creates a new OpenAI client. Setting a base url allows using any
+// OpenAI compatible endpoint, e.g. Ollama, which only receives an API key when
+// api_key_env is set explicitly.
 func NewOpenAIClient(c config.OpenAIConfig) (*OpenAIClient, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
+	// retries are handled by the RetryClient
+	opts := []option.RequestOption{option.WithMaxRetries(0)}
+
+	apiKeyEnv := c.APIKeyEnv
+	if apiKeyEnv == "" && c.BaseURL == "" {
+		apiKeyEnv = "OPENAI_API_KEY"
+	}
+	apiKey := ""
+	if apiKeyEnv != "" {
+		apiKey = os.Getenv(apiKeyEnv)
+	}
+	switch {
+	case apiKey != "":
+		opts = append(opts, option.WithAPIKey(apiKey))
+	case apiKeyEnv != "":
+		return nil, fmt.Errorf("%s environment variable is not set", apiKeyEnv)
+	default:
+		// the client reads OPENAI_API_KEY by default, never send it to a custom endpoint
+		opts = append(opts, option.WithHeaderDel("authorization"))
 	}
-	client := openai.NewClient(
-		option.WithAPIKey(apiKey),
-	)
+
+	if c.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(c.BaseURL))
+	}
+	for k, v := range c.Headers {
+		opts = append(opts, option.WithHeader(k, v))
+	}
+	for k, env := range c.HeadersEnv {
+		opts = append(opts, option.WithHeader(k, os.Getenv(env)))
+	}
+
+	log.Debug("creating openai client", "base_url", c.BaseURL, "api_key_env", apiKeyEnv, "api_key", apiKey != "")
+	client := openai.NewClient(opts...)
 
 	return &OpenAIClient{
 		client: &client,
@@ -34,48 +62,117 @@ func NewOpenAIClient(c config.OpenAIConfig) (*OpenAIClient, error) {
 	}, nil
 }
 
-// GenerateFieldDefaults generates default values for fields based on a prepared prompt
-func (c *OpenAIClient) GenerateFieldDefaults(ctx context.Context, prompt string) (FieldDefaults, error) {
-	log.Debug("generating commit defaults", "client", "openai", "model", c.config.Model)
-	defaults := FieldDefaults{}
+// GenerateFieldDefaults generates default values for fields based on a prepared prompt.
+// Alternative suggestions are requested as multiple choices of the completion.
+func (c *OpenAIClient) GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error) {
+	log.Debug("generating commit defaults", "client", "openai", "model", c.config.Model, "structured_output", c.config.StructuredOutput, "count", prompt.Count)
 
-	chatCompletion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	chatCompletion, err := c.client.Chat.Completions.New(ctx, c.params(prompt))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	log.Debug(
+		"received AI response",
+		"choices",
+		len(chatCompletion.Choices),
+		"total_tokens",
+		chatCompletion.Usage.TotalTokens,
+	)
+
+	responses := make([]string, len(chatCompletion.Choices))
+	for i, choice := range chatCompletion.Choices {
+		responses[i] = choice.Message.Content
+	}
+	return suggestions(prompt, responses)
+}
+
+// StreamFieldDefaults generates default values like GenerateFieldDefaults, streaming the
+// completion and reporting the values of the first choice as they arrive
+func (c *OpenAIClient) StreamFieldDefaults(ctx context.Context, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error) {
+	if !c.config.Stream {
+		return c.GenerateFieldDefaults(ctx, prompt)
+	}
+	log.Debug("streaming commit defaults", "client", "openai", "model", c.config.Model, "structured_output", c.config.StructuredOutput, "count", prompt.Count)
+
+	stream := c.client.Chat.Completions.NewStreaming(ctx, c.params(prompt))
+	defer stream.Close()
+
+	responses := make([]string, 0)
+	chunks := 0
+	for stream.Next() {
+		chunks++
+		for _, choice := range stream.Current().Choices {
+			i := int(choice.Index)
+			for len(responses) <= i {
+				responses = append(responses, "")
+			}
+			responses[i] += choice.Delta.Content
+			if i == 0 && choice.Delta.Content != "" {
+				partial(ParsePartialFieldDefaults(responses[0], prompt.Fields))
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	log.Debug("received AI response", "choices", len(responses), "chunks", chunks)
+	return suggestions(prompt, responses)
+}
+
+func (c *OpenAIClient) params(prompt Prompt) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(prompt),
+			openai.UserMessage(prompt.Text),
 		},
 		Model:               openai.ChatModel(c.config.Model),
 		MaxCompletionTokens: openai.Int(int64(c.config.MaxCompletionTokens)),
 		Temperature:         openai.Float(c.config.Temperature),
-	})
-
-	if err != nil {
-		return defaults, fmt.Errorf("failed to generate commit message: %w", err)
 	}
-
-	if len(chatCompletion.Choices) == 0 {
-		return defaults, fmt.Errorf("no response from AI")
+	if prompt.Count > 1 {
+		params.N = openai.Int(int64(prompt.Count))
 	}
-
-	if len(chatCompletion.Choices) > 1 {
-		log.Debug("multiple AI response choices returned", "count", len(chatCompletion.Choices))
+	if c.config.StructuredOutput {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "field_defaults",
+					Schema: prompt.Schema(),
+					Strict: openai.Bool(true),
+				},
+			},
+		}
 	}
+	return params
+}
 
-	response := chatCompletion.Choices[0].Message.Content
-	if response == "" {
-		return defaults, fmt.Errorf("AI response is empty")
+// suggestions parses the response of each choice, skipping invalid responses
+func suggestions(prompt Prompt, responses []string) ([]FieldDefaults, error) {
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no response from AI")
 	}
 
-	log.Debug(
-		"received AI response",
-		"response",
-		response,
-		"total_tokens",
-		chatCompletion.Usage.TotalTokens,
-	)
+	suggestions := make([]FieldDefaults, 0, len(responses))
+	err := fmt.Errorf("AI response is empty")
+	for i, response := range responses {
+		log.Debug("AI response choice", "index", i, "response", response)
+		if response == "" {
+			continue
+		}
 
-	if err := json.Unmarshal([]byte(response), &defaults); err != nil {
-		return defaults, fmt.Errorf("failed to unmarshal AI response: %w", err)
+		var defaults FieldDefaults
+		defaults, err = ParseFieldDefaults(response)
+		if err != nil {
+			log.Debug("skipping invalid AI response choice", "index", i, "error", err)
+			continue
+		}
+		suggestions = append(suggestions, defaults.Validate(prompt.Fields))
 	}
 
-	return defaults, nil
+	if len(suggestions) == 0 {
+		return nil, err
+	}
+	return suggestions, nil
 }
@@ -3,38 +3,185 @@ package ai
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/commit"
 	"github.com/kristofferahl/mavis/internal/pkg/config"
+	"github.com/kristofferahl/mavis/internal/pkg/diff"
+	"github.com/kristofferahl/mavis/internal/pkg/redact"
 )
 
-// GeneratePrompt creates a prompt for AI-powered commit message generation
-func GeneratePrompt(config *config.Config, gitDiff string, gitBranch string) (string, error) {
-	if gitDiff == "" {
-		return "", fmt.Errorf("git diff is empty, nothing to commit")
-	}
-
-	fields, err := json.Marshal(config.Fields)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal fields to JSON: %w", err)
-	}
-
-	prompt := fmt.Sprintf(`# Commit Message Generation Prompt
+// DefaultPromptTemplate is the built-in prompt, used unless ai.prompt is configured
+const DefaultPromptTemplate = `# Commit Message Generation Prompt
 Generate default values for each field, based on the git diff and branch.
 Respond with a JSON object where the key match the field Title and the value is the suggested default value.
 The response must be a valid JSON object and contain no wrapping characters.
-%s
+Leave fields with a show_when condition empty unless the condition holds for the other values, it references the format keys of the fields.
+{{.Guidance}}
 
 Fields (json):
-%s
-
+{{.Fields}}
+{{if .Commits}}
+Recent commit messages of the repository, follow their style for the values:
+{{join "\n---\n" .Commits}}
+{{end}}
 Git branch:
-%s
+{{.Branch}}
 
 Git diff:
-%s`, config.AI.CustomPrompt, string(fields), gitBranch, gitDiff)
+{{.Diff}}`
+
+// PromptData is the data available in prompt templates
+type PromptData struct {
+	// Fields is the JSON of the configured fields
+	Fields string
+	Branch string
+	// Diff is the staged diff, filtered and redacted
+	Diff string
+	// Commits are recent commit messages of the repository
+	Commits commit.List
+	// Files are the paths of all staged files
+	Files commit.List
+	// Guidance is the custom prompt of the config
+	Guidance string
+}
+
+// RenderTemplate renders a prompt template, supporting the same filters as commit templates
+func RenderTemplate(text string, data PromptData) (string, error) {
+	t, err := commit.Parse(text)
+	if err != nil {
+		return "", err
+	}
+	b := strings.Builder{}
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Prompt is a prepared prompt for AI-powered commit message generation
+type Prompt struct {
+	Text   string
+	Fields []*config.Field
+
+	// Count is the number of alternative suggestions to generate
+	Count int
+
+	// Branch, Diff, Files and Examples are the inputs of the prompt, the diff filtered and redacted
+	Branch   string
+	Diff     string
+	Files    []string
+	Examples []string
+}
+
+// Schema returns the JSON schema of the expected response
+func (p Prompt) Schema() map[string]any {
+	return Schema(p.Fields)
+}
+
+// WithHints returns a copy of the prompt including the current field values, asking
+// the AI to keep the values that fit the changes and to improve the others
+func (p Prompt) WithHints(hints FieldDefaults) Prompt {
+	values := FieldDefaults{}
+	for k, v := range hints {
+		if l, ok := v.([]string); ok && len(l) == 0 {
+			continue
+		}
+		if v != nil && fmt.Sprintf("%v", v) != "" {
+			values[k] = v
+		}
+	}
+	if len(values) == 0 {
+		return p
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		log.Debug("failed to marshal hints", "error", err)
+		return p
+	}
+
+	p.Text = fmt.Sprintf(`%s
+
+Current values (json), keep the values that fit the changes and improve the others:
+%s`, p.Text, string(b))
+	return p
+}
+
+// GeneratePrompt creates a prompt for AI-powered commit message generation. Examples are
+// recent commit messages of the repository, included within the configured size budget.
+func GeneratePrompt(config *config.Config, gitDiff string, gitBranch string, examples []string) (Prompt, error) {
+	if gitDiff == "" {
+		return Prompt{}, fmt.Errorf("git diff is empty, nothing to commit")
+	}
+
+	files := make([]string, 0)
+	for _, f := range diff.Parse(gitDiff) {
+		files = append(files, f.Path)
+	}
+
+	gitDiff = diff.Filter(gitDiff, config.AI.Diff)
+
+	if config.AI.Redact.Enabled {
+		r, err := redact.New(config.AI.Redact)
+		if err != nil {
+			return Prompt{}, err
+		}
+		var summary redact.Summary
+		gitDiff, summary = r.Redact(gitDiff)
+		if len(summary) > 0 {
+			log.Debug("redacted secrets from git diff", summary.KeyVals()...)
+		}
+	}
+
+	fields, err := json.Marshal(config.Fields)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to marshal fields to JSON: %w", err)
+	}
+
+	examples = budget(examples, config.AI.Examples.MaxBytes)
+
+	text := config.AI.Prompt
+	if text == "" {
+		text = DefaultPromptTemplate
+	}
+	prompt, err := RenderTemplate(text, PromptData{
+		Fields:   string(fields),
+		Branch:   gitBranch,
+		Diff:     gitDiff,
+		Commits:  examples,
+		Files:    files,
+		Guidance: config.AI.CustomPrompt,
+	})
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to render prompt template: %w", err)
+	}
 
 	log.Debug("generated prompt for AI", "prompt", prompt)
 
-	return prompt, nil
+	return Prompt{
+		Text:     prompt,
+		Fields:   config.Fields,
+		Count:    max(config.AI.Suggestions, 1),
+		Branch:   gitBranch,
+		Diff:     gitDiff,
+		Files:    files,
+		Examples: examples,
+	}, nil
+}
+
+// budget returns the examples fitting within max bytes, in order
+func budget(examples []string, maxBytes int) []string {
+	included := make([]string, 0, len(examples))
+	size := 0
+	for _, e := range examples {
+		if maxBytes > 0 && size+len(e) > maxBytes {
+			log.Debug("example exceeds the prompt budget", "bytes", len(e), "remaining", maxBytes-size)
+			continue
+		}
+		size += len(e)
+		included = append(included, e)
+	}
+	return included
 }
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/openai/openai-go"
+)
+
+// retryBackoff is the delay before the first retry, doubled for each following retry
+const retryBackoff = 500 * time.Millisecond
+
+// RetryClient wraps a client, limiting the duration of each request and retrying requests
+// failing with a rate limit or server error
+type RetryClient struct {
+	client   Client
+	provider string
+	timeout  time.Duration
+	retries  int
+}
+
+// NewRetryClient creates a client retrying failed requests of the client
+func NewRetryClient(client Client, provider string, timeout time.Duration, retries int) *RetryClient {
+	return &RetryClient{
+		client:   client,
+		provider: provider,
+		timeout:  timeout,
+		retries:  retries,
+	}
+}
+
+// GenerateFieldDefaults generates suggestions, retrying failed requests
+func (c *RetryClient) GenerateFieldDefaults(ctx context.Context, prompt Prompt) ([]FieldDefaults, error) {
+	return c.StreamFieldDefaults(ctx, prompt, nil)
+}
+
+// StreamFieldDefaults streams suggestions when supported by the client, retrying failed requests
+func (c *RetryClient) StreamFieldDefaults(ctx context.Context, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error) {
+	for attempt := 0; ; attempt++ {
+		suggestions, err := c.attempt(ctx, prompt, partial)
+		if err == nil {
+			return suggestions, nil
+		}
+		if attempt >= c.retries || !retryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		backoff := retryBackoff << attempt
+		log.Debug("retrying AI request", "provider", c.provider, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (c *RetryClient) attempt(ctx context.Context, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	suggestions, err := Generate(ctx, c.client, prompt, partial)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("request timed out after %s: %w", c.timeout, err)
+	}
+	return suggestions, err
+}
+
+// retryable returns true for errors caused by rate limits or server errors
+func retryable(err error) bool {
+	status := 0
+	var openaiErr *openai.Error
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &openaiErr):
+		status = openaiErr.StatusCode
+	case errors.As(err, &apiErr):
+		status = apiErr.StatusCode
+	}
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+// Schema returns a JSON schema describing the field defaults expected from the AI.
+// All fields are listed as required, optional fields are expected to be empty when
+// not applicable, as strict structured output doesn't allow optional properties. Fields
+// without options, e.g. when loading them failed, have no enum, as an empty enum can't
+// be satisfied. Their values are dropped by Validate instead.
+func Schema(fields []*config.Field) map[string]any {
+	properties := make(map[string]any)
+	required := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		p := map[string]any{}
+		switch f.Type {
+		case "confirm":
+			p["type"] = "boolean"
+		case "select":
+			enum := make([]string, 0, len(f.Options)+1)
+			for _, o := range f.Options {
+				enum = append(enum, o.Value)
+			}
+			if !f.Required || f.ShowWhen != "" {
+				enum = append(enum, "")
+			}
+			p["type"] = "string"
+			if len(f.Options) > 0 {
+				p["enum"] = enum
+			}
+		case "multiselect":
+			enum := make([]string, 0, len(f.Options))
+			for _, o := range f.Options {
+				enum = append(enum, o.Value)
+			}
+			items := map[string]any{"type": "string"}
+			if len(enum) > 0 {
+				items["enum"] = enum
+			}
+			p["type"] = "array"
+			p["items"] = items
+		default:
+			p["type"] = "string"
+		}
+		if f.Description != "" {
+			p["description"] = f.Description
+		}
+		properties[f.Title] = p
+		required = append(required, f.Title)
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// ParseFieldDefaults parses a JSON object from an AI response, tolerating code
+// fences and text surrounding the object
+func ParseFieldDefaults(response string) (FieldDefaults, error) {
+	defaults := FieldDefaults{}
+
+	s := strings.TrimSpace(response)
+	start := strings.Index(s, "{")
+	end := strings.LastIndex(s, "}")
+	if start < 0 || end < start {
+		return defaults, fmt.Errorf("no JSON object found in AI response")
+	}
+
+	if err := json.Unmarshal([]byte(s[start:end+1]), &defaults); err != nil {
+		return defaults, fmt.Errorf("failed to unmarshal AI response: %w", err)
+	}
+	return defaults, nil
+}
+
+// Validate coerces the values to the types of the fields, removing values that don't
+// match a field, can't be coerced or break the validation rules of the field, e.g. a
+// select value that isn't an option, and values of fields hidden by the other values
+func (d FieldDefaults) Validate(fields []*config.Field) FieldDefaults {
+	valid := FieldDefaults{}
+	for key, value := range d {
+		f := field(fields, key)
+		if f == nil {
+			log.Debug("dropping AI value for unknown field", "field", key, "value", value)
+			continue
+		}
+		v, err := f.Coerce(value)
+		if err == nil {
+			err = check(f, v)
+		}
+		if err != nil {
+			log.Debug("dropping invalid AI value", "field", key, "value", value, "error", err)
+			continue
+		}
+		valid[key] = v
+	}
+	for title := range config.HiddenFields(fields, valid) {
+		if _, ok := valid[title]; ok {
+			log.Debug("dropping AI value for hidden field", "field", title)
+			delete(valid, title)
+		}
+	}
+	return valid
+}
+
+func field(fields []*config.Field, title string) *config.Field {
+	for _, f := range fields {
+		if f.Title == title {
+			return f
+		}
+	}
+	return nil
+}
+
+// check validates a coerced value against the rules of the field, each option of a
+// multiselect field on its own
+func check(f *config.Field, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return f.Check(v)
+	case []string:
+		for _, o := range v {
+			if err := f.Check(o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+// StreamingClient is implemented by clients able to report partial suggestions while
+// the response is streamed. The partial func receives the values of the first suggestion
+// parsed so far, string values may be incomplete.
+type StreamingClient interface {
+	Client
+	StreamFieldDefaults(ctx context.Context, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error)
+}
+
+// Generate generates suggestions, streaming partial values to the partial func when supported by the client
+func Generate(ctx context.Context, client Client, prompt Prompt, partial func(FieldDefaults)) ([]FieldDefaults, error) {
+	if s, ok := client.(StreamingClient); ok && partial != nil {
+		return s.StreamFieldDefaults(ctx, prompt, partial)
+	}
+	return client.GenerateFieldDefaults(ctx, prompt)
+}
+
+// ParsePartialFieldDefaults parses the values of an incomplete JSON object, as received
+// while streaming. Values of unknown fields and values that can't be coerced, like
+// incomplete select values, are left out.
+func ParsePartialFieldDefaults(response string, fields []*config.Field) FieldDefaults {
+	defaults := FieldDefaults{}
+
+	s := response
+	start := strings.Index(s, "{")
+	if start < 0 {
+		return defaults
+	}
+	if err := json.Unmarshal([]byte(completeJSON(s[start:])), &defaults); err != nil {
+		return FieldDefaults{}
+	}
+
+	for key, value := range defaults {
+		f := field(fields, key)
+		if f == nil {
+			delete(defaults, key)
+			continue
+		}
+		v, err := f.Coerce(value)
+		if err != nil {
+			delete(defaults, key)
+			continue
+		}
+		defaults[key] = v
+	}
+	return defaults
+}
+
+// completeJSON closes an incomplete JSON document, cutting it after the last complete
+// value. An unterminated string value is kept and closed.
+func completeJSON(s string) string {
+	var (
+		closers   []byte
+		inString  bool
+		escaped   bool
+		isKey     bool
+		expectKey bool
+		literal   bool
+		safe      int
+		safeClose []byte
+	)
+
+	closed := func(s string, closers []byte) string {
+		b := []byte(s)
+		for i := len(closers) - 1; i >= 0; i-- {
+			b = append(b, closers[i])
+		}
+		return string(b)
+	}
+	mark := func(i int) {
+		safe = i
+		safeClose = append(safeClose[:0], closers...)
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if !isKey {
+					mark(i + 1)
+				}
+			}
+			continue
+		}
+
+		if literal && strings.IndexByte(",}] \t\r\n", c) >= 0 {
+			literal = false
+			mark(i)
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			isKey = len(closers) > 0 && closers[len(closers)-1] == '}' && expectKey
+		case '{':
+			closers = append(closers, '}')
+			expectKey = true
+			mark(i + 1)
+		case '[':
+			closers = append(closers, ']')
+			mark(i + 1)
+		case '}', ']':
+			if len(closers) > 0 {
+				closers = closers[:len(closers)-1]
+			}
+			mark(i + 1)
+		case ',':
+			expectKey = len(closers) > 0 && closers[len(closers)-1] == '}'
+		case ':':
+			expectKey = false
+		case ' ', '\t', '\r', '\n':
+		default:
+			literal = true
+		}
+	}
+
+	if inString && !isKey {
+		v := s
+		if escaped {
+			v = v[:len(v)-1]
+		}
+		// drop an incomplete unicode escape
+		if i := strings.LastIndex(v, `\u`); i >= 0 && len(v)-i < 6 && !strings.HasSuffix(v[:i], `\`) {
+			v = v[:i]
+		}
+		return closed(v+`"`, closers)
+	}
+	return closed(s[:safe], safeClose)
+}
@@ -0,0 +1,34 @@
+package commit
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Condition is an expression evaluated like the pipeline of an if action against
+// template values, e.g. `.breaking` or `eq .type "feat"`
+type Condition struct {
+	t *template.Template
+}
+
+// ParseCondition parses a condition, the expression may be wrapped in {{ }}
+func ParseCondition(expr string) (*Condition, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "{{") && strings.HasSuffix(expr, "}}") {
+		expr = strings.TrimSpace(expr[2 : len(expr)-2])
+	}
+	t, err := Parse("{{if " + expr + "}}true{{end}}")
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{t: t}, nil
+}
+
+// Eval returns true when the condition holds for the template values
+func (c *Condition) Eval(data []TemplateValue) (bool, error) {
+	b := strings.Builder{}
+	if err := c.t.Execute(&b, valueMap(data)); err != nil {
+		return false, err
+	}
+	return b.String() == "true", nil
+}
@@ -1,9 +1,14 @@
 package commit
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/charmbracelet/log"
 )
 
+const valuePlaceholder = "{{value}}"
+
 func NewRenderer(template string) *Renderer {
 	return &Renderer{
 		template: template,
@@ -13,12 +18,18 @@ func NewRenderer(template string) *Renderer {
 type Renderer struct {
 	template   string
 	lastRender string
+	lastErr    error
 }
 
 func (c *Renderer) String() string {
 	return c.lastRender
 }
 
+// Err returns the error of the last render
+func (c *Renderer) Err() error {
+	return c.lastErr
+}
+
 func (c *Renderer) Linebreaks() int {
 	count := 0
 	r := '\n'
@@ -34,32 +45,114 @@ type TemplateValue struct {
 	Key    string
 	Value  any
 	Format string
+	// Separator joins the values of a list before it is formatted
+	Separator string
+}
+
+// Bool is a boolean template value, printed as yes/no but usable in conditionals
+type Bool bool
+
+func (b Bool) String() string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// ListSeparator separates the values of a list when printed
+const ListSeparator = ", "
+
+// List is a multi-value template value, printed comma separated but usable with range
+type List []string
+
+func (l List) String() string {
+	return strings.Join(l, ListSeparator)
+}
+
+// resolve applies the format to the value. Values formatted with a plain {{value}}
+// keep their type so that booleans and lists can be used in conditionals and ranges.
+// Lists with a separator are joined and formatted as a whole, otherwise each value is formatted.
+func (cd TemplateValue) resolve() any {
+	switch v := cd.Value.(type) {
+	case bool:
+		if cd.Format == valuePlaceholder {
+			return Bool(v)
+		}
+		return cd.format(Bool(v).String())
+
+	case string:
+		return cd.format(v)
+
+	case []string:
+		if cd.Separator != "" {
+			return cd.format(strings.Join(v, cd.Separator))
+		}
+		l := make(List, 0, len(v))
+		for _, s := range v {
+			l = append(l, cd.format(s))
+		}
+		return l
+
+	case []any:
+		l := make([]string, 0, len(v))
+		for _, s := range v {
+			l = append(l, toString(s))
+		}
+		cd.Value = l
+		return cd.resolve()
+
+	default:
+		return cd.format(toString(v))
+	}
 }
 
-func (c *Renderer) Render(data []TemplateValue) string {
+func (cd TemplateValue) format(s string) string {
+	if len(s) > 0 {
+		s = strings.ReplaceAll(cd.Format, valuePlaceholder, s)
+	}
+	return s
+}
+
+// Render renders the template with the values. Templates in legacy form that fail to parse
+// or execute fall back to placeholder replacement, other templates return the error.
+func (c *Renderer) Render(data []TemplateValue) (string, error) {
+	values := valueMap(data)
+
 	s := strings.TrimPrefix(c.template, "\n")
-	for _, cd := range data {
-		fv := ""
-		switch v := cd.Value.(type) {
-		case bool:
-			if v {
-				fv = "yes"
-			} else {
-				fv = "no"
-			}
-
-		case string:
-			fv = v
-
-		default:
-			panic("unsupported type")
+	b := strings.Builder{}
+	t, err := Parse(s)
+	if err == nil {
+		err = t.Execute(&b, values)
+	}
+	if err != nil {
+		if !IsLegacy(s) {
+			c.lastRender, c.lastErr = "", fmt.Errorf("failed to render template, %w", err)
+			return "", c.lastErr
 		}
+		log.Debug("failed to execute legacy template, falling back to placeholder replacement", "error", err)
+		b.Reset()
+		b.WriteString(replace(s, values))
+	}
+
+	c.lastRender, c.lastErr = strings.TrimSpace(b.String()), nil
+	return c.String(), nil
+}
 
-		if len(fv) > 0 {
-			fv = strings.ReplaceAll(cd.Format, "{{value}}", fv)
+// valueMap resolves the template values by key, the first value of a key taking precedence
+func valueMap(data []TemplateValue) map[string]any {
+	values := make(map[string]any)
+	for _, cd := range data {
+		if _, ok := values[cd.Key]; !ok {
+			values[cd.Key] = cd.resolve()
 		}
-		s = strings.ReplaceAll(s, "{{"+cd.Key+"}}", fv)
 	}
-	c.lastRender = strings.TrimSpace(s)
-	return c.String()
+	return values
+}
+
+// replace substitutes {{key}} placeholders without evaluating the template
+func replace(s string, values map[string]any) string {
+	for k, v := range values {
+		s = strings.ReplaceAll(s, "{{"+k+"}}", toString(v))
+	}
+	return s
 }
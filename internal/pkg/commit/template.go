@@ -0,0 +1,157 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// placeholder matches the legacy {{key}} syntax
+var placeholder = regexp.MustCompile(`{{(-?\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*-?)}}`)
+
+// keywords are bare template actions that must not be rewritten as keys
+var keywords = map[string]bool{
+	"end":      true,
+	"else":     true,
+	"break":    true,
+	"continue": true,
+	"nil":      true,
+	"true":     true,
+	"false":    true,
+}
+
+// legacyAction matches a template action, legacyPlaceholder an action of the syntax
+// that preceded the template engine, which allowed any key, e.g. {{breaking-glyph}}
+var (
+	legacyAction      = regexp.MustCompile(`{{[^{}]*}}`)
+	legacyPlaceholder = regexp.MustCompile(`^{{[A-Za-z0-9_-]+}}$`)
+)
+
+// IsLegacy returns true when all actions of the template are legacy {{key}} placeholders
+func IsLegacy(text string) bool {
+	for _, action := range legacyAction.FindAllString(text, -1) {
+		if !legacyPlaceholder.MatchString(action) {
+			return false
+		}
+	}
+	return true
+}
+
+// RenameLegacy renames the keys of the legacy placeholders of a template to valid
+// identifiers, so that templates with keys like {{breaking-glyph}} can be parsed. It
+// returns the template and the original key of each identifier.
+func RenameLegacy(text string) (string, map[string]string) {
+	keys := make(map[string]string)
+	text = legacyAction.ReplaceAllStringFunc(text, func(s string) string {
+		if !legacyPlaceholder.MatchString(s) {
+			return s
+		}
+		key := strings.Trim(s, "{}")
+		if keywords[key] {
+			return s
+		}
+		name := fmt.Sprintf("legacy%d", len(keys))
+		keys[name] = key
+		return "{{." + name + "}}"
+	})
+	return text, keys
+}
+
+// Validate checks that a commit template parses, unless it is in legacy form
+func Validate(text string) error {
+	if _, err := Parse(text); err != nil && !IsLegacy(text) {
+		return err
+	}
+	return nil
+}
+
+// Parse parses a commit template. Bare {{key}} placeholders are rewritten to {{.key}}
+// so that templates written before the template engine existed keep working.
+func Parse(text string) (*template.Template, error) {
+	text = placeholder.ReplaceAllStringFunc(text, func(s string) string {
+		m := placeholder.FindStringSubmatch(s)
+		if keywords[m[2]] {
+			return s
+		}
+		return "{{" + m[1] + "." + m[2] + m[3] + "}}"
+	})
+	return template.New("commit").
+		Funcs(funcs).
+		Option("missingkey=error").
+		Parse(text)
+}
+
+var funcs = template.FuncMap{
+	"lower": func(v any) string {
+		return strings.ToLower(toString(v))
+	},
+	"upper": func(v any) string {
+		return strings.ToUpper(toString(v))
+	},
+	"trim": func(v any) string {
+		return strings.TrimSpace(toString(v))
+	},
+	"default": func(def string, v any) string {
+		s := toString(v)
+		if s == "" {
+			return def
+		}
+		return s
+	},
+	"wrap": func(width int, v any) string {
+		return wrap(toString(v), width)
+	},
+	"indent": func(n int, v any) string {
+		pad := strings.Repeat(" ", n)
+		lines := strings.Split(toString(v), "\n")
+		for i, l := range lines {
+			if l != "" {
+				lines[i] = pad + l
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+	"join": func(sep string, v any) string {
+		if l, ok := v.(List); ok {
+			return strings.Join(l, sep)
+		}
+		return toString(v)
+	},
+}
+
+func toString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// wrap breaks lines longer than width at word boundaries, keeping existing line breaks
+func wrap(s string, width int) string {
+	if width < 1 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		words := strings.Fields(line)
+		if len(words) == 0 || len(line) <= width {
+			continue
+		}
+		b := strings.Builder{}
+		n := 0
+		for _, w := range words {
+			if n > 0 && n+1+len(w) > width {
+				b.WriteString("\n")
+				n = 0
+			} else if n > 0 {
+				b.WriteString(" ")
+				n++
+			}
+			b.WriteString(w)
+			n += len(w)
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
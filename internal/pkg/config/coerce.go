@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Coerce converts a value to the type of the field. Strings are trimmed, booleans of confirm
+// fields are parsed and select options are matched by value or key, ignoring case.
+// Multiselect values are lists, a string is split on commas.
+// An error is returned when the value can't be converted.
+func (f *Field) Coerce(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch f.Type {
+	case "confirm":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0", "":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid value for %s: %v, must be true or false", f.Title, value)
+
+	case "select":
+		s, err := f.coerceString(value)
+		if err != nil || s == "" {
+			return s, err
+		}
+		return f.coerceOption(s)
+
+	case "multiselect":
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case []string:
+			for _, s := range v {
+				items = append(items, s)
+			}
+		case string:
+			for _, s := range strings.Split(v, ",") {
+				items = append(items, s)
+			}
+		default:
+			return nil, fmt.Errorf("invalid value for %s: %v, must be a list", f.Title, value)
+		}
+
+		selected := make([]string, 0, len(items))
+		for _, item := range items {
+			s, err := f.coerceString(item)
+			if err != nil {
+				return nil, err
+			}
+			if s == "" {
+				continue
+			}
+			o, err := f.coerceOption(s)
+			if err != nil {
+				return nil, err
+			}
+			if !contains(selected, o) {
+				selected = append(selected, o)
+			}
+		}
+		return selected, nil
+
+	default:
+		s, err := f.coerceString(value)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+func (f *Field) coerceString(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid value for %s: %v, must be a string", f.Title, value)
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// coerceOption returns the value of the option matching the string, by value or key ignoring case
+func (f *Field) coerceOption(s string) (string, error) {
+	for _, o := range f.Options {
+		if o.Value == s {
+			return o.Value, nil
+		}
+	}
+	for _, o := range f.Options {
+		if strings.EqualFold(o.Value, s) || (o.Key != "" && strings.EqualFold(o.Key, s)) {
+			return o.Value, nil
+		}
+	}
+	return "", f.invalidOption(s)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Field) invalidOption(value string) error {
+	valid := make([]string, 0, len(f.Options))
+	for _, o := range f.Options {
+		valid = append(valid, o.Value)
+	}
+	return fmt.Errorf("invalid value for %s: %q, must be one of %s", f.Title, value, strings.Join(valid, ", "))
+}
+
+// CoerceValues coerces the provided values, keyed by field title, to the types of the fields.
+// Values of unknown fields are returned as is.
+func (c *Config) CoerceValues(values map[string]interface{}) (map[string]interface{}, error) {
+	errs := make([]error, 0)
+	coerced := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		coerced[key] = value
+	}
+	for _, f := range c.Fields {
+		value, ok := values[f.Title]
+		if !ok {
+			continue
+		}
+		v, err := f.Coerce(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		coerced[f.Title] = v
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return coerced, nil
+}
@@ -5,27 +5,117 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/commit"
 	yaml "gopkg.in/yaml.v3"
 )
 
 type OpenAIConfig struct {
-	Model               string  `yaml:"model,omitempty" json:"model,omitempty"`
-	MaxCompletionTokens int     `yaml:"max_completion_tokens,omitempty" json:"max_completion_tokens,omitempty"`
-	Temperature         float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	Model               string            `yaml:"model,omitempty" json:"model,omitempty"`
+	MaxCompletionTokens int               `yaml:"max_completion_tokens,omitempty" json:"max_completion_tokens,omitempty"`
+	Temperature         float64           `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	BaseURL             string            `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	APIKeyEnv           string            `yaml:"api_key_env,omitempty" json:"api_key_env,omitempty"`
+	Headers             map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	HeadersEnv          map[string]string `yaml:"headers_env,omitempty" json:"headers_env,omitempty"`
+	StructuredOutput    bool              `yaml:"structured_output" json:"structured_output"`
+	Stream              bool              `yaml:"stream" json:"stream"`
+}
+
+type AnthropicConfig struct {
+	Model       string  `yaml:"model,omitempty" json:"model,omitempty"`
+	MaxTokens   int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	Temperature float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	APIKeyEnv   string  `yaml:"api_key_env,omitempty" json:"api_key_env,omitempty"`
+	BaseURL     string  `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+}
+
+type DiffConfig struct {
+	Ignore       []string `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+	MaxFileBytes int      `yaml:"max_file_bytes,omitempty" json:"max_file_bytes,omitempty"`
+	MaxBytes     int      `yaml:"max_bytes,omitempty" json:"max_bytes,omitempty"`
+}
+
+type ExamplesConfig struct {
+	Count    int  `yaml:"count,omitempty" json:"count,omitempty"`
+	Lint     bool `yaml:"lint" json:"lint"`
+	MaxBytes int  `yaml:"max_bytes,omitempty" json:"max_bytes,omitempty"`
+}
+
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	TTL     time.Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+type RedactConfig struct {
+	Enabled    bool     `yaml:"enabled" json:"enabled"`
+	Entropy    float64  `yaml:"entropy,omitempty" json:"entropy,omitempty"`
+	HexEntropy float64  `yaml:"hex_entropy,omitempty" json:"hex_entropy,omitempty"`
+	Patterns   []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
 }
 
 type AIConfig struct {
-	Enabled      bool         `yaml:"enabled,omitempty" json:"enabled,omitempty"`
-	Provider     string       `yaml:"provider,omitempty" json:"provider,omitempty"`
-	CustomPrompt string       `yaml:"custom_prompt,omitempty" json:"custom_prompt,omitempty"`
-	OpenAI       OpenAIConfig `yaml:"openai,omitempty" json:"openai,omitempty"`
+	Enabled      bool            `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Provider     string          `yaml:"provider,omitempty" json:"provider,omitempty"`
+	CustomPrompt string          `yaml:"custom_prompt,omitempty" json:"custom_prompt,omitempty"`
+	Prompt       string          `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Suggestions  int             `yaml:"suggestions,omitempty" json:"suggestions,omitempty"`
+	OpenAI       OpenAIConfig    `yaml:"openai,omitempty" json:"openai,omitempty"`
+	Anthropic    AnthropicConfig `yaml:"anthropic,omitempty" json:"anthropic,omitempty"`
+	Diff         DiffConfig      `yaml:"diff,omitempty" json:"diff,omitempty"`
+	Redact       RedactConfig    `yaml:"redact,omitempty" json:"redact,omitempty"`
+	Examples     ExamplesConfig  `yaml:"examples,omitempty" json:"examples,omitempty"`
+	Cache        CacheConfig     `yaml:"cache,omitempty" json:"cache,omitempty"`
+
+	Timeout   time.Duration    `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Retries   int              `yaml:"retries,omitempty" json:"retries,omitempty"`
+	Fallbacks []ProviderConfig `yaml:"fallbacks,omitempty" json:"fallbacks,omitempty"`
+}
+
+// ProviderConfig is an AI provider to fall back to, settings not configured use the defaults of the provider
+type ProviderConfig struct {
+	Provider  string          `yaml:"provider" json:"provider"`
+	OpenAI    OpenAIConfig    `yaml:"openai,omitempty" json:"openai,omitempty"`
+	Anthropic AnthropicConfig `yaml:"anthropic,omitempty" json:"anthropic,omitempty"`
+}
+
+func (p *ProviderConfig) UnmarshalYAML(n *yaml.Node) error {
+	type plain ProviderConfig
+	v := plain{
+		OpenAI:    defaultOpenAIConfig(),
+		Anthropic: defaultAnthropicConfig(),
+	}
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	*p = ProviderConfig(v)
+	return nil
+}
+
+// WithProvider returns a copy of the config using the provider, without fallbacks
+func (c AIConfig) WithProvider(p ProviderConfig) AIConfig {
+	c.Provider = p.Provider
+	c.OpenAI = p.OpenAI
+	c.Anthropic = p.Anthropic
+	c.Fallbacks = nil
+	return c
+}
+
+type MCPConfig struct {
+	Instructions string `yaml:"instructions,omitempty" json:"instructions,omitempty"`
+}
+
+type LintConfig struct {
+	SubjectMaxLength int `yaml:"subject_max_length,omitempty" json:"subject_max_length,omitempty"`
 }
 
 type Config struct {
 	path      string
+	project   string
 	processed []string
+	origins   map[string]string
 
 	Include []Include `yaml:"include,omitempty" json:"include,omitempty"`
 
@@ -36,20 +126,49 @@ type Config struct {
 	Fields []*Field `yaml:"fields" json:"fields"`
 
 	AI AIConfig `yaml:"ai,omitempty" json:"ai,omitempty"`
+
+	MCP MCPConfig `yaml:"mcp,omitempty" json:"mcp,omitempty"`
+
+	Lint LintConfig `yaml:"lint,omitempty" json:"lint,omitempty"`
+
+	// AllowProjectCommands allows the options commands of project configs to run
+	AllowProjectCommands bool `yaml:"allow_project_commands,omitempty" json:"allow_project_commands,omitempty"`
+	// AllowProjectAI allows project configs to enable AI and choose the providers, endpoints, keys and headers
+	AllowProjectAI bool `yaml:"allow_project_ai,omitempty" json:"allow_project_ai,omitempty"`
+}
+
+func defaultOpenAIConfig() OpenAIConfig {
+	return OpenAIConfig{
+		Model:               "gpt-4.1-mini",
+		MaxCompletionTokens: 500,
+		Temperature:         0.2,
+		StructuredOutput:    true,
+		Stream:              true,
+	}
+}
+
+func defaultAnthropicConfig() AnthropicConfig {
+	return AnthropicConfig{
+		Model:       "claude-haiku-4-5",
+		MaxTokens:   500,
+		Temperature: 0.2,
+		BaseURL:     "https://api.anthropic.com",
+	}
 }
 
 func New(path string) *Config {
 	c := Config{
 		path:      path,
 		processed: make([]string, 0),
+		origins:   make(map[string]string),
 
 		Theme: "charm",
 		Chip:  "",
 
 		Template: `
-{{type}}{{scope}}{{breaking_glyph}}: {{description}}
+{{.type}}{{.scope}}{{if .breaking}}!{{end}}: {{.description}}
 
-{{breaking_body}}{{body}}`,
+{{if .breaking}}BREAKING CHANGE: {{end}}{{.body | wrap 72}}`,
 
 		Fields: make([]*Field, 0),
 
@@ -57,11 +176,40 @@ func New(path string) *Config {
 			Enabled:      false,
 			Provider:     "openai",
 			CustomPrompt: "",
-			OpenAI: OpenAIConfig{
-				Model:               "gpt-4.1-mini",
-				MaxCompletionTokens: 500,
-				Temperature:         0.2,
+			Suggestions:  3,
+			Timeout:      30 * time.Second,
+			Retries:      2,
+			OpenAI:       defaultOpenAIConfig(),
+			Anthropic:    defaultAnthropicConfig(),
+			Diff: DiffConfig{
+				Ignore: []string{
+					"go.sum",
+					"*.lock",
+					"package-lock.json",
+					"vendor/**",
+					"node_modules/**",
+				},
+				MaxFileBytes: 20000,
+				MaxBytes:     60000,
+			},
+			Redact: RedactConfig{
+				Enabled:    true,
+				Entropy:    4.5,
+				HexEntropy: 3,
 			},
+			Examples: ExamplesConfig{
+				Count:    0,
+				Lint:     true,
+				MaxBytes: 4000,
+			},
+			Cache: CacheConfig{
+				Enabled: true,
+				TTL:     24 * time.Hour,
+			},
+		},
+
+		Lint: LintConfig{
+			SubjectMaxLength: 72,
 		},
 	}
 
@@ -121,24 +269,8 @@ func New(path string) *Config {
 		Description: "if yes, describe the breaking change in detail",
 		Formatting: []FormattingRule{
 			{
-				Key:    "breaking_glyph",
-				Format: "!",
-				When:   "true",
-			},
-			{
-				Key:    "breaking_glyph",
-				Format: "",
-				When:   "false",
-			},
-			{
-				Key:    "breaking_body",
-				Format: "BREAKING CHANGE: ",
-				When:   "true",
-			},
-			{
-				Key:    "breaking_body",
-				Format: "",
-				When:   "false",
+				Key:    "breaking",
+				Format: "{{value}}",
 			},
 		},
 	})
@@ -166,6 +298,13 @@ func (c *Config) Exists() bool {
 
 func (c *Config) Read() error {
 	err := c.read(c.path)
+	if err == nil {
+		if project, ok := findProject(); ok {
+			log.Debug("project config found", "file", project)
+			c.project = project
+			err = c.read(project)
+		}
+	}
 	log.Debug("configuration parsed", "files", c.processed, "error", err != nil)
 	return err
 }
@@ -177,20 +316,31 @@ func (c *Config) read(path string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read config file, %w", err)
 	}
-	if err := yaml.Unmarshal(b, c); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
 		return fmt.Errorf("failed to unmarshal config, %w", err)
 	}
+	if root {
+		// the root config replaces the defaults
+		if err := yaml.Unmarshal(b, c); err != nil {
+			return fmt.Errorf("failed to unmarshal config, %w", err)
+		}
+		c.trackRoot(&doc, path)
+	} else if err := c.merge(&doc, path); err != nil {
+		return fmt.Errorf("failed to merge config %s, %w", path, err)
+	}
 
 	pwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get current working directory, %w", err)
 	}
 
-	if c.path == path {
+	if root {
 		log.Debug("applying includes from root config")
+		env := NewEnvironment(pwd)
 		for _, i := range c.Include {
-			if i.Match(pwd) {
-				log.Debug("conditon match, including", "condition", i.When, "path", i.Path)
+			if i.Match(env) {
+				log.Debug("conditon match, including", "include", i)
 				includePath, err := resolvePath(i.Path)
 				if err != nil {
 					return fmt.Errorf("failed to resolve include path, %w", err)
@@ -199,7 +349,7 @@ func (c *Config) read(path string) error {
 					return err
 				}
 			} else {
-				log.Debug("condition mismatch, skipping", "condition", i.When, "path", i.Path)
+				log.Debug("condition mismatch, skipping", "include", i)
 			}
 		}
 	}
@@ -235,9 +385,27 @@ func (c *Config) Validate() error {
 	if c.Template == "" {
 		errs = append(errs, fmt.Errorf("template is required"))
 	}
+	if err := commit.Validate(c.Template); err != nil {
+		errs = append(errs, fmt.Errorf("invalid template, %w", err))
+	}
 	if len(c.Fields) < 1 {
 		errs = append(errs, fmt.Errorf("at least one field is required"))
 	}
+	if _, err := commit.Parse(c.AI.Prompt); err != nil {
+		errs = append(errs, fmt.Errorf("invalid ai prompt template, %w", err))
+	}
+	if _, err := commit.Parse(c.MCP.Instructions); err != nil {
+		errs = append(errs, fmt.Errorf("invalid mcp instructions template, %w", err))
+	}
+	if err := c.validateShowWhen(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.validateRules(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.validateOptionsFrom(); err != nil {
+		errs = append(errs, err)
+	}
 
 	if len(errs) > 0 {
 		return fmt.Errorf("invalid config, %w", errors.Join(errs...))
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/charmbracelet/huh"
 	"github.com/kristofferahl/mavis/internal/pkg/commit"
@@ -18,6 +19,10 @@ type Field struct {
 	Default     interface{}      `yaml:"default,omitempty" json:"default,omitempty"`
 	Formatting  []FormattingRule `yaml:"format,omitempty" json:"format,omitempty"`
 	Options     []SelectOption   `yaml:"options,omitempty" json:"options,omitempty"`
+	OptionsFrom *OptionsSource   `yaml:"options_from,omitempty" json:"-"`
+	ShowWhen    string           `yaml:"show_when,omitempty" json:"show_when,omitempty"`
+	Rules       *Rules           `yaml:"validate,omitempty" json:"validate,omitempty"`
+	Merge       string           `yaml:"merge,omitempty" json:"-"`
 }
 
 type SelectOption struct {
@@ -29,37 +34,70 @@ func (f *Field) SetRef(ref huh.Field) {
 	f.ref = ref
 }
 
+// Value returns the current value of the huh.Field reference
+func (f *Field) Value() interface{} {
+	if f.ref == nil {
+		return nil
+	}
+	return f.ref.GetValue()
+}
+
 func (f *Field) TemplateValues() (values []commit.TemplateValue) {
+	return f.TemplateValuesFrom(f.ref.GetValue())
+}
+
+// TemplateValuesFrom returns template values using the provided value instead of the huh.Field reference.
+// Keys without a matching rule are returned empty so that every key is defined in the template.
+func (f *Field) TemplateValuesFrom(value interface{}) (values []commit.TemplateValue) {
+	if s, ok := value.(string); ok && f.Type == "confirm" {
+		value, _ = strconv.ParseBool(s)
+	}
+	matched := make(map[string]bool)
 	for _, rule := range f.Formatting {
-		val := fmt.Sprintf("%v", f.ref.GetValue())
-		if rule.When == "" || val == rule.When {
+		if matched[rule.Key] {
+			continue
+		}
+		if rule.When == "" || when(value, rule.When) {
+			matched[rule.Key] = true
 			values = append(values, commit.TemplateValue{
-				Key:    rule.Key,
-				Value:  f.ref.GetValue(),
-				Format: rule.Format,
+				Key:       rule.Key,
+				Value:     value,
+				Format:    rule.Format,
+				Separator: rule.Separator,
 			})
 		}
 	}
-	return
-}
-
-// TemplateValuesFrom returns template values using the provided value instead of the huh.Field reference
-func (f *Field) TemplateValuesFrom(value interface{}) (values []commit.TemplateValue) {
 	for _, rule := range f.Formatting {
-		val := fmt.Sprintf("%v", value)
-		if rule.When == "" || val == rule.When {
+		if !matched[rule.Key] {
+			matched[rule.Key] = true
 			values = append(values, commit.TemplateValue{
-				Key:    rule.Key,
-				Value:  value,
-				Format: rule.Format,
+				Key:   rule.Key,
+				Value: f.empty(),
 			})
 		}
 	}
 	return
 }
 
+// empty returns the empty value of the field type
+func (f *Field) empty() interface{} {
+	if f.Type == "multiselect" {
+		return []string{}
+	}
+	return ""
+}
+
+// when returns true when the value equals the condition of a rule, or a list value contains it
+func when(value interface{}, condition string) bool {
+	if l, ok := value.([]string); ok {
+		return contains(l, condition)
+	}
+	return fmt.Sprintf("%v", value) == condition
+}
+
 type FormattingRule struct {
-	Key    string `yaml:"key" json:"key"`
-	Format string `yaml:"format" json:"format"`
-	When   string `yaml:"when,omitempty" json:"when,omitempty"`
+	Key       string `yaml:"key" json:"key"`
+	Format    string `yaml:"format" json:"format"`
+	When      string `yaml:"when,omitempty" json:"when,omitempty"`
+	Separator string `yaml:"separator,omitempty" json:"separator,omitempty"`
 }
@@ -0,0 +1,15 @@
+package config
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// git runs a git command in the current directory and returns its trimmed output
+func git(args ...string) (string, error) {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"unicode"
+)
+
+// verbs are common commit message verbs, their inflected forms are flagged by the imperative rule
+var verbs = []string{
+	"add", "adjust", "allow", "apply", "avoid", "build", "bump", "cache", "change", "check",
+	"clean", "configure", "convert", "correct", "create", "define", "delete", "deprecate",
+	"detect", "disable", "document", "drop", "enable", "ensure", "expose", "extract", "fetch",
+	"fix", "format", "generate", "handle", "ignore", "implement", "improve", "include",
+	"increase", "introduce", "limit", "load", "log", "make", "merge", "migrate", "move",
+	"optimize", "parse", "pass", "prevent", "print", "read", "reduce", "refactor", "release",
+	"remove", "rename", "render", "reorder", "replace", "report", "require", "reset",
+	"resolve", "restore", "return", "revert", "rewrite", "run", "save", "set", "show",
+	"simplify", "skip", "sort", "split", "start", "stop", "store", "support", "switch",
+	"sync", "test", "tidy", "trim", "tweak", "update", "upgrade", "use", "validate", "wrap",
+	"write",
+}
+
+// irregular maps irregular past forms to their verb
+var irregular = map[string]string{
+	"built": "build",
+	"made":  "make",
+	"ran":   "run",
+	"wrote": "write",
+}
+
+// inflections maps the third person, past and gerund forms of the verbs to the verb
+var inflections = func() map[string]string {
+	m := make(map[string]string)
+	for k, v := range irregular {
+		m[k] = v
+	}
+	for _, v := range verbs {
+		last := v[len(v)-1:]
+		forms := []string{v + "s", v + "ed", v + "ing", v + last + "ed", v + last + "ing"}
+		switch {
+		case strings.HasSuffix(v, "e"):
+			forms = append(forms, v+"d", v[:len(v)-1]+"ing")
+		case strings.HasSuffix(v, "y"):
+			forms = append(forms, v[:len(v)-1]+"ies", v[:len(v)-1]+"ied")
+		case strings.HasSuffix(v, "s"), strings.HasSuffix(v, "x"), strings.HasSuffix(v, "ch"), strings.HasSuffix(v, "sh"):
+			forms = append(forms, v+"es")
+		}
+		for _, f := range forms {
+			if f != v {
+				m[f] = v
+			}
+		}
+	}
+	return m
+}()
+
+// nonImperative returns the first word of the value and its verb when it is an inflected
+// form of a known verb, e.g. "added" or "fixes"
+func nonImperative(value string) (string, string, bool) {
+	word := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(word) == 0 {
+		return "", "", false
+	}
+	first := strings.ToLower(word[0])
+	verb, ok := inflections[first]
+	return word[0], verb, ok
+}
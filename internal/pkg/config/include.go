@@ -1,6 +1,9 @@
 package config
 
 import (
+	"encoding/json"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -8,21 +11,170 @@ import (
 type Include struct {
 	When string `yaml:"when,omitempty" json:"when,omitempty"`
 	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	Condition `yaml:",inline"`
+}
+
+// Condition matches the environment mavis runs in. All conditions that are set must match.
+type Condition struct {
+	Dir    string      `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Remote string      `yaml:"remote,omitempty" json:"remote,omitempty"`
+	Branch string      `yaml:"branch,omitempty" json:"branch,omitempty"`
+	File   string      `yaml:"file,omitempty" json:"file,omitempty"`
+	Env    string      `yaml:"env,omitempty" json:"env,omitempty"`
+	All    []Condition `yaml:"all,omitempty" json:"all,omitempty"`
+	Any    []Condition `yaml:"any,omitempty" json:"any,omitempty"`
+	Not    *Condition  `yaml:"not,omitempty" json:"not,omitempty"`
 }
 
-func (i Include) Match(path string) bool {
-	if i.When == "" {
-		return true
+func (i Include) String() string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func (i Include) Match(env *Environment) bool {
+	if i.When != "" && !matchDir(i.When, env.Dir) {
+		return false
 	}
+	return i.Condition.Match(env)
+}
 
-	path, err := filepath.Abs(path)
+// Match returns true if all conditions that are set match the environment
+func (c Condition) Match(env *Environment) bool {
+	if c.Dir != "" && !matchDir(c.Dir, env.Dir) {
+		return false
+	}
+	if c.Remote != "" && !env.matchRemote(c.Remote) {
+		return false
+	}
+	if c.Branch != "" {
+		if ok, _ := path.Match(c.Branch, env.branch()); !ok {
+			return false
+		}
+	}
+	if c.File != "" {
+		matches, err := filepath.Glob(filepath.Join(env.root(), c.File))
+		if err != nil || len(matches) == 0 {
+			return false
+		}
+	}
+	if c.Env != "" && !matchEnv(c.Env) {
+		return false
+	}
+	for _, a := range c.All {
+		if !a.Match(env) {
+			return false
+		}
+	}
+	if len(c.Any) > 0 {
+		match := false
+		for _, a := range c.Any {
+			if a.Match(env) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	if c.Not != nil && c.Not.Match(env) {
+		return false
+	}
+	return true
+}
+
+func matchDir(when string, dir string) bool {
+	dir, err := filepath.Abs(dir)
 	if err != nil {
 		return false
 	}
 
-	whenPath, err := resolvePath(i.When)
+	whenPath, err := resolvePath(when)
 	if err != nil {
 		return false
 	}
-	return strings.HasPrefix(path, whenPath)
+	return strings.HasPrefix(dir, whenPath)
+}
+
+// matchEnv matches NAME (set and not empty) or NAME=pattern
+func matchEnv(expr string) bool {
+	name, pattern, hasPattern := strings.Cut(expr, "=")
+	value := os.Getenv(name)
+	if !hasPattern {
+		return value != ""
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// Environment holds the state include conditions are matched against, git state is resolved on first use
+type Environment struct {
+	Dir string
+
+	resolved bool
+	toplevel string
+	current  string
+	remotes  []string
+}
+
+func NewEnvironment(dir string) *Environment {
+	return &Environment{Dir: dir}
+}
+
+func (e *Environment) resolve() {
+	if e.resolved {
+		return
+	}
+	e.resolved = true
+
+	e.toplevel, _ = git("rev-parse", "--show-toplevel")
+	e.current, _ = git("branch", "--show-current")
+	if output, err := git("config", "--get-regexp", `^remote\..*\.url$`); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			if _, url, ok := strings.Cut(line, " "); ok {
+				e.remotes = append(e.remotes, normalizeRemote(url))
+			}
+		}
+	}
+}
+
+// root returns the root of the git repository, or the working directory outside of a repository
+func (e *Environment) root() string {
+	e.resolve()
+	if e.toplevel == "" {
+		return e.Dir
+	}
+	return e.toplevel
+}
+
+func (e *Environment) branch() string {
+	e.resolve()
+	return e.current
+}
+
+func (e *Environment) matchRemote(pattern string) bool {
+	e.resolve()
+	pattern = normalizeRemote(pattern)
+	for _, r := range e.remotes {
+		if ok, _ := path.Match(pattern, r); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRemote turns remote urls like git@github.com:acme/app.git and
+// https://github.com/acme/app into github.com/acme/app
+func normalizeRemote(url string) string {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	} else if host, p, ok := strings.Cut(url, ":"); ok && !strings.Contains(host, "/") {
+		url = host + "/" + p
+	}
+	if i := strings.Index(url, "@"); i >= 0 && i < strings.Index(url+"/", "/") {
+		url = url[i+1:]
+	}
+	url = strings.TrimSuffix(url, "/")
+	return strings.TrimSuffix(url, ".git")
 }
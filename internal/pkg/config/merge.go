@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// MergePatch updates the properties set in the included field, or appends it if it doesn't exist (default)
+	MergePatch = "patch"
+	// MergeReplace replaces the existing field as a whole
+	MergeReplace = "replace"
+	// MergeRemove removes the existing field
+	MergeRemove = "remove"
+)
+
+// Origin describes the file a configuration value was read from
+type Origin struct {
+	Key  string
+	File string
+}
+
+// Origins returns the file each configuration value was read from, values not listed are defaults
+func (c *Config) Origins() []Origin {
+	origins := make([]Origin, 0, len(c.origins))
+	for k, f := range c.origins {
+		origins = append(origins, Origin{Key: k, File: f})
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		return origins[i].Key < origins[j].Key
+	})
+	return origins
+}
+
+// trackRoot records the origin of all values in the root config document
+func (c *Config) trackRoot(doc *yaml.Node, path string) {
+	root := mapping(doc)
+	if root == nil {
+		return
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "fields" {
+			c.track(key.Value, value, path)
+			continue
+		}
+		for _, n := range value.Content {
+			var f Field
+			if err := n.Decode(&f); err == nil {
+				c.track(fmt.Sprintf("fields[%s]", f.Title), n, path)
+			}
+		}
+	}
+}
+
+// merge applies an included config document on top of the current config. Scalar
+// values and settings are overridden, fields are merged by title.
+func (c *Config) merge(doc *yaml.Node, path string) error {
+	root := mapping(doc)
+	if root == nil {
+		return nil
+	}
+
+	if path == c.project {
+		// settings are restored after decoding, so that aliases and merge keys can't bypass them
+		restore := c.protect(path)
+		defer restore()
+	}
+
+	settings := &yaml.Node{Kind: yaml.MappingNode}
+	var fields *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "fields":
+			fields = value
+		case "include":
+			log.Debug("ignoring includes of included config", "file", path)
+		default:
+			settings.Content = append(settings.Content, key, value)
+			c.track(key.Value, value, path)
+		}
+	}
+
+	if err := settings.Decode(c); err != nil {
+		return fmt.Errorf("failed to unmarshal config, %w", err)
+	}
+
+	if fields == nil {
+		return nil
+	}
+	for _, n := range fields.Content {
+		if err := c.mergeField(n, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeField merges a single included field into the fields of the config
+func (c *Config) mergeField(n *yaml.Node, path string) error {
+	var f Field
+	if err := n.Decode(&f); err != nil {
+		return fmt.Errorf("failed to unmarshal field, %w", err)
+	}
+	if f.Title == "" {
+		return fmt.Errorf("failed to merge field from %s, title is required", path)
+	}
+
+	key := fmt.Sprintf("fields[%s]", f.Title)
+	i := c.fieldIndex(f.Title)
+	merge := f.Merge
+	f.Merge = ""
+	if f.OptionsFrom != nil {
+		// owned by the file, however it was written, as aliases and merge keys aren't tracked
+		f.OptionsFrom.origin = path
+	}
+
+	switch {
+	case merge == MergeRemove:
+		if i < 0 {
+			log.Debug("field to remove not found", "field", f.Title, "file", path)
+			return nil
+		}
+		log.Debug("removing field", "field", f.Title, "file", path)
+		c.Fields = append(c.Fields[:i], c.Fields[i+1:]...)
+		c.untrack(key)
+
+	case i < 0:
+		log.Debug("appending field", "field", f.Title, "file", path)
+		c.Fields = append(c.Fields, &f)
+		c.track(key, n, path)
+
+	case merge == MergeReplace:
+		log.Debug("replacing field", "field", f.Title, "file", path)
+		c.Fields[i] = &f
+		c.untrack(key)
+		c.track(key, n, path)
+
+	case merge == MergePatch || merge == "":
+		log.Debug("patching field", "field", f.Title, "file", path)
+		existing := c.Fields[i]
+		props := &yaml.Node{Kind: yaml.MappingNode}
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			k, v := n.Content[j], n.Content[j+1]
+			switch k.Value {
+			case "title", "merge":
+				continue
+			case "options":
+				// options are added, replacing existing options with the same value
+				for _, o := range f.Options {
+					existing.addOption(o)
+				}
+			default:
+				props.Content = append(props.Content, k, v)
+			}
+			c.track(key+"."+k.Value, v, path)
+		}
+		if err := props.Decode(existing); err != nil {
+			return fmt.Errorf("failed to patch field %s, %w", f.Title, err)
+		}
+		if f.OptionsFrom != nil && existing.OptionsFrom != nil {
+			existing.OptionsFrom.origin = path
+		}
+
+	default:
+		return fmt.Errorf("invalid merge strategy %q for field %s, must be one of %s, %s or %s", merge, f.Title, MergePatch, MergeReplace, MergeRemove)
+	}
+	return nil
+}
+
+func (c *Config) fieldIndex(title string) int {
+	for i, f := range c.Fields {
+		if f.Title == title {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *Field) addOption(o SelectOption) {
+	for i, existing := range f.Options {
+		if existing.Value == o.Value {
+			f.Options[i] = o
+			return
+		}
+	}
+	f.Options = append(f.Options, o)
+}
+
+// track records the origin of a value and all values nested in it
+func (c *Config) track(key string, n *yaml.Node, path string) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.track(key+"."+n.Content[i].Value, n.Content[i+1], path)
+		}
+		return
+	}
+	c.origins[key] = path
+}
+
+// untrack removes the origins of a value and all values nested in it
+func (c *Config) untrack(key string) {
+	for k := range c.origins {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(c.origins, k)
+		}
+	}
+}
+
+// mapping returns the top level mapping node of a document
+func mapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/version"
+)
+
+// DefaultOptionsTimeout is the time an options command may run, unless configured
+const DefaultOptionsTimeout = 5 * time.Second
+
+// OptionsSource loads the options of a select field from the output of a command or the
+// lines of a file, one option per line. A tab separates the value from the key shown.
+type OptionsSource struct {
+	Command string        `yaml:"command,omitempty" json:"command,omitempty"`
+	File    string        `yaml:"file,omitempty" json:"file,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Cache   time.Duration `yaml:"cache,omitempty" json:"cache,omitempty"`
+
+	// origin is the last config file setting any part of the source
+	origin string
+}
+
+// ResolveOptions adds the options loaded from the options sources of the fields to their
+// configured options. Commands and files are relative to the root of the git repository.
+// Commands of the project config only run when allowed by the user config. Sources that
+// fail are skipped with a warning, leaving the configured options.
+func (c *Config) ResolveOptions() {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		if root, err = os.Getwd(); err != nil {
+			log.Warn("failed to get working directory, skipping options sources", "error", err)
+			return
+		}
+	}
+
+	for _, f := range c.Fields {
+		s := f.OptionsFrom
+		if s == nil {
+			continue
+		}
+
+		project := c.project != "" && s.origin == c.project
+		if project && s.Command != "" && !c.allowProjectCommands() {
+			log.Warn("skipping options command of project config, set allow_project_commands in the user config to run it", "field", f.Title, "command", s.Command)
+			continue
+		}
+
+		var options []SelectOption
+		if s.Command != "" {
+			options, err = s.run(root)
+		} else {
+			options, err = s.read(root, project)
+		}
+		if err != nil {
+			log.Warn("failed to load options", "field", f.Title, "error", err)
+			continue
+		}
+		log.Debug("loaded options", "field", f.Title, "count", len(options))
+		for _, o := range options {
+			f.addOption(o)
+		}
+	}
+}
+
+// allowProjectCommands returns true when commands of the project config are allowed,
+// which the project config can't allow itself
+func (c *Config) allowProjectCommands() bool {
+	return c.AllowProjectCommands && c.origins["allow_project_commands"] != c.project
+}
+
+// run runs the command, using the cached options while they haven't expired
+func (s *OptionsSource) run(dir string) ([]SelectOption, error) {
+	cache := s.cacheFile(dir)
+	if options, ok := s.cached(cache); ok {
+		log.Debug("using cached options", "file", cache)
+		return options, nil
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultOptionsTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.Command)
+	cmd.Dir = dir
+	// don't wait for children of the shell holding on to the output after a timeout
+	cmd.WaitDelay = 100 * time.Millisecond
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("command %q timed out after %s", s.Command, timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("command %q failed, %w", s.Command, err)
+	}
+
+	options := parseOptions(string(output))
+	if s.Cache > 0 {
+		if err := writeOptions(cache, options); err != nil {
+			log.Debug("failed to cache options", "file", cache, "error", err)
+		}
+	}
+	return options, nil
+}
+
+// read reads the file, files of the project config must be within the repository
+func (s *OptionsSource) read(dir string, project bool) ([]SelectOption, error) {
+	path := s.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if project {
+		// symlinks committed to the repository must not lead outside of it
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve options file, %w", err)
+		}
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve repository root, %w", err)
+		}
+		if !within(resolved, root) {
+			return nil, fmt.Errorf("options file %s of project config is outside of the repository", s.File)
+		}
+		path = resolved
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read options file, %w", err)
+	}
+	return parseOptions(string(b)), nil
+}
+
+func (s *OptionsSource) cacheFile(dir string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	h := sha256.Sum256([]byte(s.Command + "\x00" + dir))
+	return filepath.Join(cacheDir, version.Name, "options", hex.EncodeToString(h[:])+".json")
+}
+
+func (s *OptionsSource) cached(file string) ([]SelectOption, bool) {
+	if s.Cache <= 0 || file == "" {
+		return nil, false
+	}
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) > s.Cache {
+		return nil, false
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var options []SelectOption
+	if err := json.Unmarshal(b, &options); err != nil {
+		return nil, false
+	}
+	return options, true
+}
+
+func writeOptions(file string, options []SelectOption) error {
+	if file == "" {
+		return fmt.Errorf("no cache dir")
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0600)
+}
+
+// parseOptions parses one option per line, skipping empty lines and comments
+func parseOptions(s string) []SelectOption {
+	options := make([]SelectOption, 0)
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		value, key, _ := strings.Cut(line, "\t")
+		options = append(options, SelectOption{
+			Key:   strings.TrimSpace(key),
+			Value: strings.TrimSpace(value),
+		})
+	}
+	return options
+}
+
+// validateOptionsFrom checks that each options source has either a command or a file
+func (c *Config) validateOptionsFrom() error {
+	for _, f := range c.Fields {
+		s := f.OptionsFrom
+		if s == nil {
+			continue
+		}
+		if (s.Command == "") == (s.File == "") {
+			return fmt.Errorf("invalid options_from of field %s, either command or file is required", f.Title)
+		}
+		if f.Type != "select" && f.Type != "multiselect" {
+			return fmt.Errorf("invalid options_from of field %s, only select and multiselect fields have options", f.Title)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// ProjectFile is the name of the project config file committed to a repository
+const ProjectFile = ".mavis.yaml"
+
+// findProject looks for a project config file, walking up from the current
+// working directory to the root of the git repository
+func findProject() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	// outside of a git repository, only the working directory is considered
+	root := dir
+	if toplevel, err := git("rev-parse", "--show-toplevel"); err == nil {
+		root = toplevel
+	}
+	log.Debug("looking for project config", "from", dir, "to", root)
+
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir || !within(parent, root) {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// within returns true if path is the same as or a subdirectory of root
+func within(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules are declarative validation rules of a field, checked in the UI, in non-interactive
+// mode, by the MCP preview_commit tool and by the linter
+type Rules struct {
+	MinLength        int      `yaml:"min_length,omitempty" json:"min_length,omitempty"`
+	MaxLength        int      `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	Pattern          string   `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Message          string   `yaml:"message,omitempty" json:"message,omitempty"`
+	ForbiddenWords   []string `yaml:"forbidden_words,omitempty" json:"forbidden_words,omitempty"`
+	NoTrailingPeriod bool     `yaml:"no_trailing_period,omitempty" json:"no_trailing_period,omitempty"`
+	LowercaseFirst   bool     `yaml:"lowercase_first,omitempty" json:"lowercase_first,omitempty"`
+	Imperative       bool     `yaml:"imperative,omitempty" json:"imperative,omitempty"`
+}
+
+// Check validates a string value, or a single option of a multiselect field, against the
+// rules of the field, returning the first rule violated. Empty values are left to the
+// required check.
+func (f *Field) Check(value string) error {
+	r := f.Rules
+	if value == "" || r == nil {
+		return nil
+	}
+
+	length := utf8.RuneCountInString(value)
+	if r.MinLength > 0 && length < r.MinLength {
+		return fmt.Errorf("must be at least %d characters", r.MinLength)
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		return fmt.Errorf("must not exceed %d characters", r.MaxLength)
+	}
+	if r.Pattern != "" {
+		p, err := compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q, %w", r.Pattern, err)
+		}
+		if !p.MatchString(value) {
+			if r.Message != "" {
+				return fmt.Errorf("%s", r.Message)
+			}
+			return fmt.Errorf("must match the pattern %s", r.Pattern)
+		}
+	}
+	for _, w := range r.ForbiddenWords {
+		if p, _ := compile(`(?i)\b` + regexp.QuoteMeta(w) + `\b`); p.MatchString(value) {
+			return fmt.Errorf("must not contain %q", w)
+		}
+	}
+	if r.NoTrailingPeriod && strings.HasSuffix(strings.TrimSpace(value), ".") {
+		return fmt.Errorf("must not end with a period")
+	}
+	if r.LowercaseFirst {
+		if first, _ := utf8.DecodeRuneInString(value); unicode.IsUpper(first) {
+			return fmt.Errorf("must start with a lowercase letter")
+		}
+	}
+	if r.Imperative {
+		if word, verb, ok := nonImperative(value); ok {
+			return fmt.Errorf("must use the imperative mood, %q instead of %q", verb, word)
+		}
+	}
+	return nil
+}
+
+// validateRules checks that the patterns of the field rules compile
+func (c *Config) validateRules() error {
+	for _, f := range c.Fields {
+		if f.Rules == nil || f.Rules.Pattern == "" {
+			continue
+		}
+		if _, err := compile(f.Rules.Pattern); err != nil {
+			return fmt.Errorf("invalid validate pattern of field %s, %w", f.Title, err)
+		}
+	}
+	return nil
+}
+
+// expressions caches the compiled expressions of the rules, as Check runs on every keystroke
+var expressions sync.Map
+
+// compile compiles an expression once, returning the cached result afterwards
+func compile(expr string) (*regexp.Regexp, error) {
+	if re, ok := expressions.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	expressions.Store(expr, re)
+	return re, nil
+}
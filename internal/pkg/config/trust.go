@@ -0,0 +1,105 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// protected are the settings a project config may not change. The AI settings control
+// whether and where the diff, keys and headers are sent and what is left out of the diff,
+// and are only applied from a project config when the user config sets allow_project_ai.
+type protected struct {
+	AllowProjectCommands bool
+	AllowProjectAI       bool
+
+	Enabled   bool
+	Provider  string
+	Fallbacks []ProviderConfig
+
+	OpenAIBaseURL      string
+	OpenAIAPIKeyEnv    string
+	OpenAIHeaders      map[string]string
+	OpenAIHeadersEnv   map[string]string
+	AnthropicBaseURL   string
+	AnthropicAPIKeyEnv string
+
+	Redact     RedactConfig
+	DiffIgnore []string
+}
+
+func (c *Config) protected() protected {
+	return protected{
+		AllowProjectCommands: c.AllowProjectCommands,
+		AllowProjectAI:       c.AllowProjectAI,
+
+		Enabled:   c.AI.Enabled,
+		Provider:  c.AI.Provider,
+		Fallbacks: append([]ProviderConfig(nil), c.AI.Fallbacks...),
+
+		OpenAIBaseURL:      c.AI.OpenAI.BaseURL,
+		OpenAIAPIKeyEnv:    c.AI.OpenAI.APIKeyEnv,
+		OpenAIHeaders:      maps.Clone(c.AI.OpenAI.Headers),
+		OpenAIHeadersEnv:   maps.Clone(c.AI.OpenAI.HeadersEnv),
+		AnthropicBaseURL:   c.AI.Anthropic.BaseURL,
+		AnthropicAPIKeyEnv: c.AI.Anthropic.APIKeyEnv,
+
+		Redact:     c.AI.Redact.clone(),
+		DiffIgnore: append([]string(nil), c.AI.Diff.Ignore...),
+	}
+}
+
+// protect returns a func restoring the settings the project config may not change
+func (c *Config) protect(path string) func() {
+	before := c.protected()
+	origins := maps.Clone(c.origins)
+	return func() {
+		after := c.protected()
+		if after.AllowProjectCommands != before.AllowProjectCommands || after.AllowProjectAI != before.AllowProjectAI {
+			log.Warn("ignoring allow_project_commands and allow_project_ai of project config, they can only be set in the user config", "file", path)
+		}
+		c.AllowProjectCommands, c.AllowProjectAI = before.AllowProjectCommands, before.AllowProjectAI
+		c.restoreOrigins(origins, "allow_project_commands", "allow_project_ai")
+
+		if before.AllowProjectAI {
+			return
+		}
+		before.AllowProjectCommands, before.AllowProjectAI = after.AllowProjectCommands, after.AllowProjectAI
+		if !reflect.DeepEqual(before, after) {
+			log.Warn("ignoring AI settings of project config, set allow_project_ai in the user config to apply them", "file", path)
+		}
+		c.AI.Enabled = before.Enabled
+		c.AI.Provider = before.Provider
+		c.AI.Fallbacks = before.Fallbacks
+		c.AI.OpenAI.BaseURL = before.OpenAIBaseURL
+		c.AI.OpenAI.APIKeyEnv = before.OpenAIAPIKeyEnv
+		c.AI.OpenAI.Headers = before.OpenAIHeaders
+		c.AI.OpenAI.HeadersEnv = before.OpenAIHeadersEnv
+		c.AI.Anthropic.BaseURL = before.AnthropicBaseURL
+		c.AI.Anthropic.APIKeyEnv = before.AnthropicAPIKeyEnv
+		c.AI.Redact = before.Redact
+		c.AI.Diff.Ignore = before.DiffIgnore
+		c.restoreOrigins(origins, "ai.enabled", "ai.provider", "ai.fallbacks", "ai.openai.base_url",
+			"ai.openai.api_key_env", "ai.openai.headers", "ai.openai.headers_env", "ai.anthropic.base_url", "ai.anthropic.api_key_env",
+			"ai.redact", "ai.diff.ignore")
+	}
+}
+
+// restoreOrigins replaces the origins of the keys, and the values nested in them, with the previous origins
+func (c *Config) restoreOrigins(previous map[string]string, keys ...string) {
+	for _, key := range keys {
+		c.untrack(key)
+		for k, f := range previous {
+			if k == key || strings.HasPrefix(k, key+".") {
+				c.origins[k] = f
+			}
+		}
+	}
+}
+
+func (r RedactConfig) clone() RedactConfig {
+	r.Patterns = append([]string(nil), r.Patterns...)
+	return r
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/commit"
+)
+
+// TemplateValuesFrom validates the provided values, keyed by field title, and returns the template values for all fields.
+// Hidden fields are not validated and their values are left out.
+func (c *Config) TemplateValuesFrom(values map[string]interface{}) ([]commit.TemplateValue, error) {
+	errs := make([]error, 0)
+	templateValues := make([]commit.TemplateValue, 0)
+	hidden := HiddenFields(c.Fields, values)
+
+	for _, field := range c.Fields {
+		value, ok := values[field.Title]
+		if !ok || hidden[field.Title] {
+			value = nil
+		}
+
+		if hidden[field.Title] {
+			log.Debug("ignoring value of hidden field", "field", field.Title)
+		} else if err := field.validate(value); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if value != nil {
+			templateValues = append(templateValues, field.TemplateValuesFrom(value)...)
+		} else {
+			// Use empty values for missing optional fields
+			templateValues = append(templateValues, field.TemplateValuesFrom(field.empty())...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return templateValues, nil
+}
+
+// validate checks a value provided for the field against its definition
+func (f *Field) validate(value interface{}) error {
+	str, isString := value.(string)
+	list, isList := value.([]string)
+
+	if f.Required {
+		if value == nil {
+			return fmt.Errorf("missing required field: %s", f.Title)
+		}
+		if (isString && str == "") || (isList && len(list) == 0) {
+			return fmt.Errorf("required field cannot be empty: %s", f.Title)
+		}
+	}
+
+	if f.Type == "select" && isString && str != "" {
+		if err := f.validOption(str); err != nil {
+			return err
+		}
+	}
+	if isString {
+		if err := f.Check(str); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", f.Title, err)
+		}
+	}
+	if f.Type == "multiselect" {
+		for _, s := range list {
+			if err := f.validOption(s); err != nil {
+				return err
+			}
+			if err := f.Check(s); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", f.Title, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) validOption(value string) error {
+	for _, o := range f.Options {
+		if o.Value == value {
+			return nil
+		}
+	}
+	return f.invalidOption(value)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/commit"
+)
+
+// HiddenFields returns the titles of the fields hidden by their show_when condition, given
+// the values keyed by field title. Conditions are evaluated in field order against the
+// template values of all fields, the values of hidden fields being empty.
+func HiddenFields(fields []*Field, values map[string]interface{}) map[string]bool {
+	data := make([][]commit.TemplateValue, len(fields))
+	for i, f := range fields {
+		value := values[f.Title]
+		if value == nil {
+			value = f.empty()
+		}
+		data[i] = f.TemplateValuesFrom(value)
+	}
+
+	hidden := make(map[string]bool)
+	for i, f := range fields {
+		if f.ShowWhen == "" {
+			continue
+		}
+		show, err := f.shown(data)
+		if err != nil {
+			log.Debug("failed to evaluate show_when, showing field", "field", f.Title, "error", err)
+			continue
+		}
+		if !show {
+			hidden[f.Title] = true
+			data[i] = f.TemplateValuesFrom(f.empty())
+		}
+	}
+	return hidden
+}
+
+// Hidden returns true when the field is hidden given the current values of the fields
+func (c *Config) Hidden(title string) bool {
+	return HiddenFields(c.Fields, c.values())[title]
+}
+
+// TemplateValues returns the template values of the current values of the fields,
+// the values of hidden fields being empty
+func (c *Config) TemplateValues() []commit.TemplateValue {
+	hidden := HiddenFields(c.Fields, c.values())
+
+	data := make([]commit.TemplateValue, 0)
+	for _, f := range c.Fields {
+		if hidden[f.Title] {
+			data = append(data, f.TemplateValuesFrom(f.empty())...)
+			continue
+		}
+		data = append(data, f.TemplateValues()...)
+	}
+	return data
+}
+
+// values returns the current values of the fields, keyed by title
+func (c *Config) values() map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, f := range c.Fields {
+		values[f.Title] = f.Value()
+	}
+	return values
+}
+
+// shown evaluates the show_when condition of the field against the template values of all fields
+func (f *Field) shown(data [][]commit.TemplateValue) (bool, error) {
+	condition, err := commit.ParseCondition(f.ShowWhen)
+	if err != nil {
+		return false, err
+	}
+	values := make([]commit.TemplateValue, 0)
+	for _, d := range data {
+		values = append(values, d...)
+	}
+	return condition.Eval(values)
+}
+
+// validateShowWhen checks that the show_when conditions parse and only reference known keys
+func (c *Config) validateShowWhen() error {
+	data := make([][]commit.TemplateValue, len(c.Fields))
+	for i, f := range c.Fields {
+		data[i] = f.TemplateValuesFrom(f.empty())
+	}
+	for _, f := range c.Fields {
+		if f.ShowWhen == "" {
+			continue
+		}
+		if _, err := f.shown(data); err != nil {
+			return fmt.Errorf("invalid show_when of field %s, %w", f.Title, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+// File is the diff of a single file
+type File struct {
+	Path    string
+	Patch   string
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// Stat returns a diffstat like summary of the file
+func (f File) Stat() string {
+	if f.Binary {
+		return fmt.Sprintf("%s | binary", f.Path)
+	}
+	return fmt.Sprintf("%s | +%d -%d", f.Path, f.Added, f.Deleted)
+}
+
+// Parse splits a unified git diff into files
+func Parse(diff string) []File {
+	files := make([]File, 0)
+	var current *File
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, File{Path: filePath(line)})
+			current = &files[len(files)-1]
+		}
+		if current == nil {
+			continue
+		}
+		current.Patch += line
+
+		switch {
+		case strings.HasPrefix(line, "Binary files ") || strings.HasPrefix(line, "GIT binary patch"):
+			current.Binary = true
+		case strings.Contains(line, "\x00"):
+			current.Binary = true
+		case strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			current.Added++
+		case strings.HasPrefix(line, "-"):
+			current.Deleted++
+		}
+	}
+	return files
+}
+
+// Filter removes ignored and binary files from the diff and keeps it within the configured
+// budgets. Omitted files are summarized as stat lines at the end of the diff.
+func Filter(diff string, c config.DiffConfig) string {
+	files := Parse(diff)
+	if len(files) == 0 {
+		return diff
+	}
+
+	b := strings.Builder{}
+	omitted := make([]string, 0)
+	for _, f := range files {
+		reason := ""
+		switch {
+		case ignored(f.Path, c.Ignore):
+			reason = "ignored"
+		case f.Binary:
+			reason = "binary"
+		case c.MaxFileBytes > 0 && len(f.Patch) > c.MaxFileBytes:
+			reason = "exceeds file budget"
+		case c.MaxBytes > 0 && b.Len()+len(f.Patch) > c.MaxBytes:
+			reason = "exceeds total budget"
+		}
+
+		if reason != "" {
+			log.Debug("omitting file from diff", "file", f.Path, "reason", reason, "bytes", len(f.Patch))
+			if f.Binary {
+				omitted = append(omitted, f.Stat())
+			} else {
+				omitted = append(omitted, fmt.Sprintf("%s (%s)", f.Stat(), reason))
+			}
+			continue
+		}
+		b.WriteString(f.Patch)
+	}
+
+	if len(omitted) > 0 {
+		b.WriteString("\nOmitted files:\n")
+		for _, o := range omitted {
+			b.WriteString(" " + o + "\n")
+		}
+	}
+
+	log.Debug("filtered diff", "files", len(files), "omitted", len(omitted), "bytes", len(diff), "filtered_bytes", b.Len())
+	return b.String()
+}
+
+// filePath returns the path of the file from a "diff --git a/path b/path" line
+func filePath(line string) string {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "diff --git "))
+	if i := strings.LastIndex(line, " b/"); i >= 0 {
+		return line[i+3:]
+	}
+	return line
+}
+
+// ignored returns true if the path matches any of the patterns. Patterns without
+// a slash match the file name in any directory, ** matches any number of directories.
+func ignored(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(p)); ok {
+				return true
+			}
+			continue
+		}
+		if glob(pattern).MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func glob(pattern string) *regexp.Regexp {
+	b := strings.Builder{}
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,109 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/version"
+)
+
+const (
+	// PrepareCommitMsg is the hook opening the commit UI for plain git commits
+	PrepareCommitMsg = "prepare-commit-msg"
+	// CommitMsg is the hook linting the final commit message
+	CommitMsg = "commit-msg"
+
+	marker = "# installed by " + version.Name
+)
+
+// Names of all hooks managed by mavis
+var Names = []string{PrepareCommitMsg, CommitMsg}
+
+// Dir returns the hooks directory of the current repository, respecting core.hooksPath
+func Dir() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Abs(strings.TrimSpace(string(output)))
+}
+
+// Script returns the content of the hook script for the named hook
+func Script(name string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s, remove with: %s hook uninstall
+exec %s hook %s "$@"
+`, marker, version.Name, version.Name, name)
+}
+
+// Install writes the mavis hooks to the hooks directory. Existing hooks not
+// installed by mavis are only replaced when force is true.
+func Install(dir string, force bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks dir, %w", err)
+	}
+
+	for _, name := range Names {
+		path := filepath.Join(dir, name)
+		installed, err := isInstalled(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !installed && !force {
+			return fmt.Errorf("hook %s already exists, use --force to replace it", path)
+		}
+	}
+
+	for _, name := range Names {
+		path := filepath.Join(dir, name)
+		log.Debug("installing hook", "path", path)
+		if err := os.WriteFile(path, []byte(Script(name)), 0755); err != nil {
+			return fmt.Errorf("failed to write hook, %w", err)
+		}
+		// replaced hooks keep their previous mode
+		if err := os.Chmod(path, 0755); err != nil {
+			return fmt.Errorf("failed to make hook executable, %w", err)
+		}
+	}
+	return nil
+}
+
+// Uninstall removes the mavis hooks from the hooks directory, leaving other hooks untouched
+func Uninstall(dir string) error {
+	for _, name := range Names {
+		path := filepath.Join(dir, name)
+		installed, err := isInstalled(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !installed {
+			log.Warn("hook was not installed by "+version.Name+", skipping", "path", path)
+			continue
+		}
+
+		log.Debug("removing hook", "path", path)
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove hook, %w", err)
+		}
+	}
+	return nil
+}
+
+func isInstalled(path string) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(b), marker), nil
+}
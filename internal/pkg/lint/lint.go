@@ -0,0 +1,296 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template/parse"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/commit"
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+// Linter checks commit messages against the structure implied by the configured template and fields
+type Linter struct {
+	config  *config.Config
+	pattern *regexp.Regexp
+	groups  map[string]capture
+}
+
+// capture maps a regexp group to the field it extracts a value for
+type capture struct {
+	field     *config.Field
+	when      string
+	separator string
+}
+
+// Result is the outcome of linting a single commit message
+type Result struct {
+	Message string
+	Errors  []error
+}
+
+// OK returns true when the message passed all checks
+func (r Result) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Subject returns the first line of the message
+func (r Result) Subject() string {
+	subject, _, _ := strings.Cut(r.Message, "\n")
+	return subject
+}
+
+// New creates a linter for the template and fields of the config
+func New(c *config.Config) (*Linter, error) {
+	text := strings.TrimSpace(c.Template)
+	keys := make(map[string]string)
+	if commit.IsLegacy(text) {
+		text, keys = commit.RenameLegacy(text)
+	}
+	t, err := commit.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template, %w", err)
+	}
+
+	l := &Linter{
+		config: c,
+		groups: make(map[string]capture),
+	}
+	b := &builder{linter: l, keys: keys}
+	b.node(t.Tree.Root)
+
+	expr := "^" + b.String() + "$"
+	log.Debug("lint pattern", "regexp", expr)
+	l.pattern, err = regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile template pattern, %w", err)
+	}
+	return l, nil
+}
+
+// Lint checks a single commit message
+func (l *Linter) Lint(message string) Result {
+	r := Result{
+		Message: Clean(message),
+	}
+
+	if r.Message == "" {
+		r.Errors = append(r.Errors, fmt.Errorf("message is empty"))
+		return r
+	}
+
+	if max, length := l.config.Lint.SubjectMaxLength, utf8.RuneCountInString(r.Subject()); max > 0 && length > max {
+		r.Errors = append(r.Errors, fmt.Errorf("subject is %d characters long, must not exceed %d", length, max))
+	}
+
+	match := l.pattern.FindStringSubmatch(r.Message)
+	if match == nil {
+		r.Errors = append(r.Errors, fmt.Errorf("message does not match the template"))
+		return r
+	}
+
+	values := make(map[string]interface{})
+	for i, name := range l.pattern.SubexpNames() {
+		c, ok := l.groups[name]
+		if !ok || match[i] == "" {
+			continue
+		}
+		if _, ok := values[c.field.Title]; ok {
+			continue
+		}
+		values[c.field.Title] = c.value(match[i])
+	}
+	log.Debug("lint values", "values", values)
+
+	if _, err := l.config.TemplateValuesFrom(values); err != nil {
+		r.Errors = append(r.Errors, unwrap(err)...)
+	}
+	return r
+}
+
+// generatedPrefixes are the subject prefixes of messages generated by git
+var generatedPrefixes = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+// Generated returns true when the message was generated by git, e.g. for a merge, a revert
+// or a fixup commit
+func Generated(message string) bool {
+	subject, _, _ := strings.Cut(Clean(message), "\n")
+	for _, p := range generatedPrefixes {
+		if strings.HasPrefix(subject, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clean removes git comment lines and surrounding whitespace from a commit message
+func Clean(message string) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (c capture) value(s string) interface{} {
+	if c.field.Type == "multiselect" {
+		if c.when != "" {
+			return []string{c.when}
+		}
+		separator := c.separator
+		if separator == "" {
+			separator = commit.ListSeparator
+		}
+		values := make([]string, 0)
+		for _, v := range strings.Split(s, separator) {
+			values = append(values, strings.TrimSpace(v))
+		}
+		return values
+	}
+	if c.when != "" {
+		return c.when
+	}
+	if c.field.Type == "confirm" {
+		return s == commit.Bool(true).String() || s == strconv.FormatBool(true)
+	}
+	return s
+}
+
+func unwrap(err error) []error {
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		return u.Unwrap()
+	}
+	return []error{err}
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// builder translates a parsed template into a regular expression
+type builder struct {
+	strings.Builder
+	linter *Linter
+	body   bool
+	// keys are the original keys of renamed legacy placeholders
+	keys map[string]string
+}
+
+func (b *builder) node(n parse.Node) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			b.node(c)
+		}
+
+	case *parse.TextNode:
+		b.text(string(n.Text))
+
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 && len(n.Pipe.Cmds) > 0 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if f, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode); ok && len(f.Ident) == 1 {
+				key := f.Ident[0]
+				if original, ok := b.keys[key]; ok {
+					key = original
+				}
+				b.key(key, len(n.Pipe.Cmds) > 1)
+				return
+			}
+		}
+		b.WriteString("(?:" + b.any() + ")?")
+
+	case *parse.IfNode:
+		b.branch(n.List, n.ElseList)
+
+	case *parse.WithNode:
+		b.branch(n.List, n.ElseList)
+
+	default:
+		b.WriteString("(?:" + b.any() + ")?")
+	}
+}
+
+// branch matches either of the lists of a conditional, or nothing
+func (b *builder) branch(list, elseList *parse.ListNode) {
+	b.WriteString("(?:")
+	b.node(list)
+	if elseList != nil {
+		b.WriteString("|")
+		b.node(elseList)
+	}
+	b.WriteString(")?")
+}
+
+// text matches literal template text, tolerating differences in whitespace
+func (b *builder) text(s string) {
+	last := 0
+	for _, loc := range whitespace.FindAllStringIndex(s, -1) {
+		b.WriteString(regexp.QuoteMeta(s[last:loc[0]]))
+		if strings.Contains(s[loc[0]:loc[1]], "\n") {
+			b.body = true
+			b.WriteString(`(?:\s*\n\s*|\s*$)`)
+		} else {
+			b.WriteString(`[ \t]*`)
+		}
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(s[last:]))
+}
+
+// key matches the formatted value of a template key, capturing the field value
+func (b *builder) key(key string, filtered bool) {
+	alternatives := make([]string, 0)
+	for _, f := range b.linter.config.Fields {
+		for _, rule := range f.Formatting {
+			if rule.Key != key {
+				continue
+			}
+			name := fmt.Sprintf("g%d", len(b.linter.groups))
+			c := capture{field: f, separator: rule.Separator}
+			var expr string
+			switch {
+			case filtered:
+				// filters may transform the value, capture it as is
+				expr = group(name, b.any())
+			case strings.Contains(rule.Format, "{{value}}"):
+				before, after, _ := strings.Cut(rule.Format, "{{value}}")
+				expr = regexp.QuoteMeta(before) + group(name, b.any()) + regexp.QuoteMeta(after)
+			case rule.Format != "" && rule.When != "":
+				// constant formats identify the value by the condition of the rule
+				c.when = rule.When
+				expr = group(name, regexp.QuoteMeta(rule.Format))
+			case rule.Format != "":
+				expr = regexp.QuoteMeta(rule.Format)
+			default:
+				continue
+			}
+			b.linter.groups[name] = c
+			alternatives = append(alternatives, expr)
+		}
+	}
+	if len(alternatives) == 0 {
+		b.WriteString("(?:" + b.any() + ")?")
+		return
+	}
+	b.WriteString("(?:" + strings.Join(alternatives, "|") + ")?")
+}
+
+func group(name, expr string) string {
+	return "(?P<" + name + ">" + expr + ")"
+}
+
+// any matches any text, limited to a single line while in the subject
+func (b *builder) any() string {
+	if b.body {
+		return `[\s\S]+?`
+	}
+	return `.+?`
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/kristofferahl/mavis/internal/pkg/ai"
 	"github.com/kristofferahl/mavis/internal/pkg/commit"
 	"github.com/kristofferahl/mavis/internal/pkg/config"
 	"github.com/kristofferahl/mavis/internal/pkg/version"
@@ -93,7 +94,8 @@ type PrepareCommitResult struct {
 	Instructions string          `json:"instructions"`
 }
 
-const mcpInstructions = `Generate field values for a git commit message based on STAGED changes only.
+// defaultInstructions is the built-in instructions template of prepare_commit, used unless mcp.instructions is configured
+const defaultInstructions = `Generate field values for a git commit message based on STAGED changes only.
 
 STEP 1 - Gather context (run these commands first):
 - git diff --cached        → View the staged changes (this is what you're committing)
@@ -108,17 +110,36 @@ STEP 2 - Analyze the changes:
 STEP 3 - Generate field values:
 - Provide values as a JSON object where keys match field titles exactly
 - For "select" fields: use one of the available option key values
+- For "multiselect" fields: use a list of the available option values
 - For "confirm" fields: use "true" or "false" (as strings)
 - For "input" and "text" fields: use appropriate string values
 - Leave optional fields empty ("") if not applicable
+- Leave fields with a "show_when" condition empty unless the condition holds; it is a template expression referencing the format keys of the other fields, e.g. ".breaking"
 
 Breaking change guidance:
-- Mark as breaking if the change removes or renames public APIs, changes function signatures, removes configuration options, or alters expected behavior in ways that require users to update their code`
+- Mark as breaking if the change removes or renames public APIs, changes function signatures, removes configuration options, or alters expected behavior in ways that require users to update their code
+{{- if .Guidance}}
+
+Additional guidance:
+{{.Guidance}}
+{{- end}}`
 
 func (s *Server) handlePrepareCommit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instructions := mcpInstructions
-	if s.config.AI.CustomPrompt != "" {
-		instructions = instructions + "\n\nAdditional guidance:\n" + s.config.AI.CustomPrompt
+	fields, err := json.Marshal(s.config.Fields)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal fields: %v", err)), nil
+	}
+
+	text := s.config.MCP.Instructions
+	if text == "" {
+		text = defaultInstructions
+	}
+	instructions, err := ai.RenderTemplate(text, ai.PromptData{
+		Fields:   string(fields),
+		Guidance: s.config.AI.CustomPrompt,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to render instructions: %v", err)), nil
 	}
 
 	result := PrepareCommitResult{
@@ -160,36 +181,22 @@ func (s *Server) handlePreviewCommit(ctx context.Context, request mcp.CallToolRe
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get repo path: %v", err)), nil
 	}
 
-	// Validate required fields and build template values
-	var templateValues []commit.TemplateValue
-	for _, field := range s.config.Fields {
-		value, ok := values[field.Title]
-		if !ok {
-			value = nil
-		}
-
-		// Check required fields
-		if field.Required {
-			if value == nil {
-				return mcp.NewToolResultError(fmt.Sprintf("missing required field: %s", field.Title)), nil
-			}
-			if str, ok := value.(string); ok && str == "" {
-				return mcp.NewToolResultError(fmt.Sprintf("required field cannot be empty: %s", field.Title)), nil
-			}
-		}
-
-		// Get template values using the provided value
-		if value != nil {
-			templateValues = append(templateValues, field.TemplateValuesFrom(value)...)
-		} else {
-			// Use empty string for missing optional fields
-			templateValues = append(templateValues, field.TemplateValuesFrom("")...)
-		}
+	// Coerce and validate values and build template values
+	values, err = s.config.CoerceValues(values)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	templateValues, err := s.config.TemplateValuesFrom(values)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	// Render the commit message
 	renderer := commit.NewRenderer(s.config.Template)
-	message := renderer.Render(templateValues)
+	message, err := renderer.Render(templateValues)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	// Store in cache
 	pc := s.cache.Store(repoPath, message)
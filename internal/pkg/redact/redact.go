@@ -0,0 +1,132 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kristofferahl/mavis/internal/pkg/config"
+)
+
+// Detector finds a kind of secret
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Detectors are the built-in secret detectors
+var Detectors = []Detector{
+	{Name: "private_key", Pattern: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----.*?-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{Name: "aws_secret_key", Pattern: regexp.MustCompile(`(?i)(aws_?secret_?(access_?)?key\W{0,5})[A-Za-z0-9/+=]{40}\b`)},
+	{Name: "github_token", Pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{Name: "gitlab_token", Pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}\b`)},
+	{Name: "slack_token", Pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}\b`)},
+	{Name: "anthropic_key", Pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_\-]{20,}`)},
+	{Name: "openai_key", Pattern: regexp.MustCompile(`\bsk-(proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{Name: "google_api_key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+}
+
+// candidate matches values that are checked for high entropy. It doesn't start with the
+// + or - of a diff line or span the = of an assignment, so only the value is masked.
+var candidate = regexp.MustCompile(`[A-Za-z0-9/_][A-Za-z0-9+/_\-]{19,}`)
+
+// hexadecimal matches values of hex digits only, which have at most 4 bits per character
+var hexadecimal = regexp.MustCompile(`^[0-9A-Fa-f]+$`)
+
+// Summary counts the secrets masked per detector
+type Summary map[string]int
+
+// KeyVals returns the summary as key value pairs for logging
+func (s Summary) KeyVals() []interface{} {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kv := make([]interface{}, 0, len(s)*2)
+	for _, k := range keys {
+		kv = append(kv, k, s[k])
+	}
+	return kv
+}
+
+// Redactor masks secrets in text
+type Redactor struct {
+	detectors  []Detector
+	entropy    float64
+	hexEntropy float64
+}
+
+// New creates a redactor using the built-in detectors and the configured patterns
+func New(c config.RedactConfig) (*Redactor, error) {
+	r := &Redactor{
+		detectors:  append([]Detector{}, Detectors...),
+		entropy:    c.Entropy,
+		hexEntropy: c.HexEntropy,
+	}
+	for i, p := range c.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q, %w", p, err)
+		}
+		r.detectors = append(r.detectors, Detector{Name: fmt.Sprintf("pattern_%d", i+1), Pattern: re})
+	}
+	return r, nil
+}
+
+// Redact masks all secrets found in the text
+func (r *Redactor) Redact(s string) (string, Summary) {
+	summary := Summary{}
+	for _, d := range r.detectors {
+		s = d.Pattern.ReplaceAllStringFunc(s, func(string) string {
+			summary[d.Name]++
+			return mask(d.Name)
+		})
+	}
+
+	if r.entropy > 0 {
+		s = candidate.ReplaceAllStringFunc(s, func(m string) string {
+			threshold := r.entropy
+			if r.hexEntropy > 0 && hexadecimal.MatchString(m) {
+				threshold = r.hexEntropy
+			}
+			if !mixed(m) || entropy(m) < threshold {
+				return m
+			}
+			summary["high_entropy"]++
+			return mask("high_entropy")
+		})
+	}
+	return s, summary
+}
+
+func mask(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// mixed returns true if the string contains both letters and digits
+func mixed(s string) bool {
+	return strings.ContainsAny(s, "0123456789") && strings.IndexFunc(s, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	}) >= 0
+}
+
+// entropy returns the Shannon entropy of the string in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	e := 0.0
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
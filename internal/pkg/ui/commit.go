@@ -1,20 +1,40 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/kristofferahl/mavis/internal/pkg/ai"
 	"github.com/kristofferahl/mavis/internal/pkg/commit"
 	"github.com/kristofferahl/mavis/internal/pkg/config"
 	"github.com/kristofferahl/mavis/internal/pkg/version"
 )
 
-func NewCommitUI(config config.Config) tea.Model {
+// Suggest generates alternative field value suggestions, using the current field values as hints.
+// Partial values of the first suggestion may be reported while the suggestions are generated.
+type Suggest func(ctx context.Context, hints ai.FieldDefaults, partial func(ai.FieldDefaults)) ([]ai.FieldDefaults, error)
+
+type suggestionsMsg struct {
+	suggestions []ai.FieldDefaults
+	requested   ai.FieldDefaults
+	err         error
+}
+
+type partialMsg struct {
+	values    ai.FieldDefaults
+	requested ai.FieldDefaults
+	next      <-chan tea.Msg
+}
+
+func NewCommitUI(config config.Config) CommitUI {
 	var theme *huh.Theme
 	switch config.Theme {
 	case "base":
@@ -48,7 +68,12 @@ func NewCommitUI(config config.Config) tea.Model {
 
 	// Fields
 	fields := make([]huh.Field, 0)
+	setters := make(map[string]func(interface{}))
+	conditional := make(map[int]string)
 	for _, f := range config.Fields {
+		if f.ShowWhen != "" {
+			conditional[len(fields)] = f.Title
+		}
 		switch f.Type {
 		case "input":
 			v := ""
@@ -64,18 +89,22 @@ func NewCommitUI(config config.Config) tea.Model {
 					if f.Required && len(s) < 1 {
 						return fmt.Errorf("must not be empty")
 					}
-					return nil
+					return f.Check(s)
 				})
 
 			f.SetRef(i)
 			fields = append(fields, i)
+			setters[f.Title] = func(value interface{}) {
+				v = fmt.Sprintf("%v", value)
+				i.Value(&v)
+			}
 
 		case "text":
 			v := ""
 			if f.Default != nil {
 				v = fmt.Sprintf("%v", f.Default)
 			}
-			i := huh.NewText().
+			t := huh.NewText().
 				Title(f.Title).
 				Description(f.Description).
 				Placeholder(f.Placeholder).
@@ -84,19 +113,25 @@ func NewCommitUI(config config.Config) tea.Model {
 					if f.Required && len(s) < 1 {
 						return fmt.Errorf("must not be empty")
 					}
-					return nil
+					return f.Check(s)
 				}).
 				ShowLineNumbers(true).
-				Lines(3).
-				WithHeight(5)
+				Lines(3)
+			i := t.WithHeight(5)
 
 			f.SetRef(i)
 			fields = append(fields, i)
+			setters[f.Title] = func(value interface{}) {
+				v = fmt.Sprintf("%v", value)
+				t.Value(&v)
+			}
 
 		case "select":
 			v := ""
-			if f.Default != nil {
-				v = fmt.Sprintf("%v", f.Default)
+			if d, err := f.Coerce(f.Default); err != nil {
+				log.Debug("ignoring invalid default value", "field", f.Title, "error", err)
+			} else if d != nil {
+				v = d.(string)
 			}
 			opts := make([]huh.Option[string], 0)
 			for _, opt := range f.Options {
@@ -119,16 +154,69 @@ func NewCommitUI(config config.Config) tea.Model {
 					if f.Required && len(s) < 1 {
 						return fmt.Errorf("must not be empty")
 					}
+					return f.Check(s)
+				})
+
+			f.SetRef(i)
+			fields = append(fields, i)
+			setters[f.Title] = func(value interface{}) {
+				if o, err := f.Coerce(value); err == nil && o != nil {
+					v = o.(string)
+					i.Value(&v)
+				}
+			}
+
+		case "multiselect":
+			v := make([]string, 0)
+			if d, err := f.Coerce(f.Default); err != nil {
+				log.Debug("ignoring invalid default value", "field", f.Title, "error", err)
+			} else if d != nil {
+				v = d.([]string)
+			}
+			options := func() []huh.Option[string] {
+				opts := make([]huh.Option[string], 0)
+				for _, opt := range f.Options {
+					key := opt.Key
+					if len(opt.Key) == 0 {
+						key = opt.Value
+					}
+					opts = append(opts, huh.NewOption(key, opt.Value))
+				}
+				return opts
+			}
+			i := huh.NewMultiSelect[string]().
+				Title(f.Title).
+				Description(f.Description).
+				Value(&v).
+				Options(options()...).
+				Validate(func(s []string) error {
+					if f.Required && len(s) < 1 {
+						return fmt.Errorf("must not be empty")
+					}
+					for _, o := range s {
+						if err := f.Check(o); err != nil {
+							return err
+						}
+					}
 					return nil
 				})
 
 			f.SetRef(i)
 			fields = append(fields, i)
+			setters[f.Title] = func(value interface{}) {
+				if l, err := f.Coerce(value); err == nil && l != nil {
+					v = l.([]string)
+					// options are recreated, as setting the value only selects options
+					i.Options(options()...)
+				}
+			}
 
 		case "confirm":
 			v := false
-			if f.Default != nil {
-				v = f.Default.(bool)
+			if d, err := f.Coerce(f.Default); err != nil {
+				log.Debug("ignoring invalid default value", "field", f.Title, "error", err)
+			} else if d != nil {
+				v = d.(bool)
 			}
 			i := huh.NewConfirm().
 				Title(f.Title).
@@ -137,6 +225,12 @@ func NewCommitUI(config config.Config) tea.Model {
 
 			f.SetRef(i)
 			fields = append(fields, i)
+			setters[f.Title] = func(value interface{}) {
+				if b, err := f.Coerce(value); err == nil && b != nil {
+					v = b.(bool)
+					i.Value(&v)
+				}
+			}
 		}
 	}
 
@@ -151,6 +245,11 @@ func NewCommitUI(config config.Config) tea.Model {
 	for i, input := range fields {
 		input.Focus()
 		groups[i] = huh.NewGroup(input)
+		if title, ok := conditional[i]; ok {
+			groups[i].WithHideFunc(func() bool {
+				return config.Hidden(title)
+			})
+		}
 	}
 
 	return CommitUI{
@@ -161,10 +260,115 @@ func NewCommitUI(config config.Config) tea.Model {
 			WithShowHelp(true),
 		Confirm: &okay,
 
-		config: config,
-		style:  style,
-		keys:   keys,
-		help:   help.New(),
+		config:  config,
+		style:   style,
+		keys:    keys,
+		help:    help.New(),
+		setters: setters,
+		spinner: spinner.New(
+			spinner.WithSpinner(spinner.Dot),
+			spinner.WithStyle(lipgloss.NewStyle().Foreground(style.Highlight)),
+		),
+	}
+}
+
+// WithSuggestions enables AI suggestions. Suggestions are generated in the background once
+// the form is started, filling in the fields that haven't been edited when they arrive.
+func (m CommitUI) WithSuggestions(ctx context.Context, suggest Suggest) CommitUI {
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.suggest = suggest
+	m.generating = true
+	m.keys.Regenerate.SetEnabled(true)
+	return m
+}
+
+// applySuggestions applies the first of the suggestions to the fields that haven't changed
+// since the suggestions were requested
+func (m CommitUI) applySuggestions(suggestions []ai.FieldDefaults, requested ai.FieldDefaults) CommitUI {
+	m.suggestions = suggestions
+	m.suggestion = 0
+	m.keys.NextSuggestion.SetEnabled(len(suggestions) > 1)
+	if len(suggestions) > 0 {
+		log.Debug("applying suggestion", "values", suggestions[0])
+		m.fill(suggestions[0], requested)
+	}
+	return m
+}
+
+// fill sets the values on the fields that haven't been edited since they were requested.
+// Values set are recorded as requested, so that later values of the same request replace them.
+func (m CommitUI) fill(values ai.FieldDefaults, requested ai.FieldDefaults) {
+	current := m.values()
+	for _, f := range m.config.Fields {
+		value, ok := values[f.Title]
+		if !ok || value == nil {
+			continue
+		}
+		if fmt.Sprintf("%v", current[f.Title]) != fmt.Sprintf("%v", requested[f.Title]) {
+			log.Debug("keeping edited value for field", "field", f.Title, "value", current[f.Title])
+			continue
+		}
+		if set, ok := m.setters[f.Title]; ok {
+			set(value)
+			requested[f.Title] = f.Value()
+		}
+	}
+}
+
+// apply sets the values of a suggestion on the fields of the form
+func (m CommitUI) apply(suggestion ai.FieldDefaults) {
+	for _, f := range m.config.Fields {
+		value, ok := suggestion[f.Title]
+		if !ok || value == nil {
+			continue
+		}
+		if set, ok := m.setters[f.Title]; ok {
+			log.Debug("setting suggested value for field", "field", f.Title, "value", value)
+			set(value)
+		}
+	}
+}
+
+// values returns the current values of the fields
+func (m CommitUI) values() ai.FieldDefaults {
+	values := ai.FieldDefaults{}
+	for _, f := range m.config.Fields {
+		values[f.Title] = f.Value()
+	}
+	return values
+}
+
+// generate requests suggestions in the background, optionally using the current values as hints.
+// Partial values and the suggestions are delivered through a channel, one message at a time.
+func (m CommitUI) generate(withHints bool) tea.Cmd {
+	ctx, suggest, requested := m.ctx, m.suggest, m.values()
+	var hints ai.FieldDefaults
+	if withHints {
+		hints = requested
+	}
+
+	ch := make(chan tea.Msg, 1)
+	send := func(msg tea.Msg) {
+		// replace partial values that haven't been received yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- msg
+	}
+	run := func() tea.Msg {
+		suggestions, err := suggest(ctx, hints, func(values ai.FieldDefaults) {
+			send(partialMsg{values: values, requested: requested, next: ch})
+		})
+		send(suggestionsMsg{suggestions: suggestions, requested: requested, err: err})
+		return nil
+	}
+	return tea.Batch(run, receive(ch))
+}
+
+func receive(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
 	}
 }
 
@@ -180,6 +384,16 @@ type CommitUI struct {
 	quitting bool
 	width    int
 	form     *huh.Form
+	setters  map[string]func(interface{})
+
+	suggestions []ai.FieldDefaults
+	suggestion  int
+	suggest     Suggest
+	generating  bool
+	suggestErr  error
+	spinner     spinner.Model
+	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
 type commitUIStyle struct {
@@ -194,6 +408,9 @@ type commitUIStyle struct {
 }
 
 func (m CommitUI) Init() tea.Cmd {
+	if m.suggest != nil {
+		return tea.Batch(m.form.Init(), m.spinner.Tick, m.generate(false))
+	}
 	return m.form.Init()
 }
 
@@ -203,16 +420,52 @@ func (m CommitUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.help.Width = msg.Width
 
+	case spinner.TickMsg:
+		if !m.generating {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case partialMsg:
+		m.fill(msg.values, msg.requested)
+		return m, receive(msg.next)
+
+	case suggestionsMsg:
+		m.generating = false
+		if msg.err != nil {
+			log.Debug("failed to generate suggestions", "error", msg.err)
+			if m.ctx.Err() == nil {
+				m.suggestErr = msg.err
+			}
+			return m, nil
+		}
+		return m.applySuggestions(msg.suggestions, msg.requested), nil
+
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.Commit):
+			m.stop()
 			q := true
 			m.Confirm = &q
 			m.quitting = true
 			return m, tea.Quit
+		case key.Matches(msg, m.keys.NextSuggestion):
+			m.suggestion = (m.suggestion + 1) % len(m.suggestions)
+			m.apply(m.suggestions[m.suggestion])
+			return m, nil
+		case key.Matches(msg, m.keys.Regenerate):
+			if m.generating {
+				return m, nil
+			}
+			m.generating = true
+			m.suggestErr = nil
+			return m, tea.Batch(m.spinner.Tick, m.generate(true))
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
 		case key.Matches(msg, m.keys.Quit):
+			m.stop()
 			q := false
 			m.Confirm = &q
 			m.quitting = true
@@ -231,6 +484,7 @@ func (m CommitUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if ok {
 		cmds = append(cmds, cmd)
 		if form.State == huh.StateCompleted {
+			m.stop()
 			return m, tea.Quit
 		}
 	}
@@ -289,9 +543,7 @@ func (m CommitUI) View() string {
 			col   = lipgloss.NewStyle().Width(width)
 			data  = make([]commit.TemplateValue, 0)
 		)
-		for _, field := range m.config.Fields {
-			data = append(data, field.TemplateValues()...)
-		}
+		data = append(data, m.config.TemplateValues()...)
 		inputCol := col.
 			Padding(0).
 			BorderStyle(s.Border).
@@ -301,7 +553,11 @@ func (m CommitUI) View() string {
 			Padding(0, s.Padding+1)
 
 		input := inputCol.Render(form.WithWidth(width).View())
-		preview := previewCol.Render(m.Commit.Render(data))
+		message, err := m.Commit.Render(data)
+		if err != nil {
+			message = err.Error()
+		}
+		preview := previewCol.Render(message + m.statusView())
 
 		row := lipgloss.JoinHorizontal(lipgloss.Top, input, preview)
 		doc.WriteString(row + "\n")
@@ -316,3 +572,26 @@ func (m CommitUI) View() string {
 	// Okay, let's render it
 	return s.Doc.Render(doc.String()) + "\n"
 }
+
+// stop cancels suggestions being generated
+func (m CommitUI) stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// statusView describes the state of the suggestions below the preview
+func (m CommitUI) statusView() string {
+	var status string
+	switch {
+	case m.generating:
+		status = fmt.Sprintf("%s generating suggestions using %s...", m.spinner.View(), m.config.AI.Provider)
+	case m.suggestErr != nil:
+		status = fmt.Sprintf("failed to generate suggestions, %s", strings.ReplaceAll(m.suggestErr.Error(), "\n", "; "))
+	case len(m.suggestions) > 1:
+		status = fmt.Sprintf("suggestion %d of %d", m.suggestion+1, len(m.suggestions))
+	default:
+		return ""
+	}
+	return "\n\n" + m.style.Base.Foreground(m.style.Subtle).Render(status)
+}
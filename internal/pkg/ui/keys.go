@@ -7,6 +7,16 @@ var keys = keyMap{
 		key.WithKeys("ctrl+a", "ctrl+s"),
 		key.WithHelp("ctrl+a / ctrl+s", "accept preview and commit"),
 	),
+	NextSuggestion: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "next suggestion"),
+		key.WithDisabled(),
+	),
+	Regenerate: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "regenerate suggestions"),
+		key.WithDisabled(),
+	),
 	Help: key.NewBinding(
 		key.WithKeys("?"),
 		key.WithHelp("?", "toggle help"),
@@ -18,9 +28,11 @@ var keys = keyMap{
 }
 
 type keyMap struct {
-	Commit key.Binding
-	Help   key.Binding
-	Quit   key.Binding
+	Commit         key.Binding
+	NextSuggestion key.Binding
+	Regenerate     key.Binding
+	Help           key.Binding
+	Quit           key.Binding
 }
 
 // ShortHelp returns keybindings to be shown in the mini help view. It's part
@@ -33,6 +45,7 @@ func (k keyMap) ShortHelp() []key.Binding {
 // key.Map interface.
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Commit, k.Help, k.Quit}, // first column
+		{k.Commit, k.Help, k.Quit},       // first column
+		{k.NextSuggestion, k.Regenerate}, // second column
 	}
 }